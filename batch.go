@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A batchError holds the failures of a batch of definitions converted with
+// --continue-on-error.
+type batchError struct {
+	failures []failure
+}
+
+// A failure is the error converting the definition at a path.
+type failure struct {
+	path string
+	err  error
+}
+
+// add records that the definition at the supplied path failed. A path that
+// fails the same way for both the composite and the claim CRD is only
+// recorded once.
+func (e *batchError) add(path string, err error) {
+	for _, f := range e.failures {
+		if f.path == path && f.err.Error() == err.Error() {
+			return
+		}
+	}
+	e.failures = append(e.failures, failure{path: path, err: err})
+}
+
+// errOrNil returns the batch error, or nil if nothing failed.
+func (e *batchError) errOrNil() error {
+	if e == nil || len(e.failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *batchError) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d definitions failed:", len(e.failures))
+	for _, f := range e.failures {
+		fmt.Fprintf(b, "\n  %s: %s", f.path, f.err)
+	}
+	return b.String()
+}
+
+// fail returns the supplied error of the definition at the supplied path. With
+// --continue-on-error it instead records the error, to be returned once the
+// whole batch was converted, and returns nil.
+func (c *config) fail(path string, err error) error {
+	if err == nil || !c.continueOnError {
+		return err
+	}
+	c.failures.add(path, err)
+	return nil
+}
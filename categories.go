@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+// Annotations that add categories to the CRDs derived from an XRD. Each holds
+// a comma separated list of categories.
+const (
+	AnnotationCompositeCategories = "xrdconvert.dev/composite-categories"
+	AnnotationClaimCategories     = "xrdconvert.dev/claim-categories"
+)
+
+// categoriesFor returns the categories of a CRD derived from the supplied XRD.
+// The XRD's own categories come first, followed by those of the supplied
+// annotation, those supplied as options and finally the built-in category.
+func categoriesFor(xrd *v1.CompositeResourceDefinition, own []string, annotation, builtin string, o *options) []string {
+	c := append([]string{}, own...)
+	for _, s := range strings.Split(xrd.GetAnnotations()[annotation], ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			c = append(c, s)
+		}
+	}
+	c = append(c, o.categories...)
+	return dedupe(append(c, builtin))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCategories(t *testing.T) {
+	annotated := strings.Replace(claimXRD, "  name: xthings.example.org\n", `  name: xthings.example.org
+  annotations:
+    xrdconvert.dev/composite-categories: infra
+    xrdconvert.dev/claim-categories: team, apps
+`, 1)
+
+	messy := strings.Replace(claimXRD, "  name: xthings.example.org\n", `  name: xthings.example.org
+  annotations:
+    xrdconvert.dev/claim-categories: " team,, team ,claim"
+`, 1)
+
+	cases := map[string]struct {
+		reason        string
+		xrd           string
+		cfg           func(cfg *config)
+		wantComposite []string
+		wantClaim     []string
+	}{
+		"BuiltIn": {
+			reason:        "Each CRD should have its built-in category.",
+			xrd:           claimXRD,
+			wantComposite: []string{CategoryComposite},
+			wantClaim:     []string{CategoryClaim},
+		},
+		"Flags": {
+			reason: "Categories supplied for one kind of CRD should not leak onto the other, and the built-in ones should not repeat.",
+			xrd:    claimXRD,
+			cfg: func(cfg *config) {
+				cfg.compositeCategories = stringsFlag{"infra"}
+				cfg.claimCategories = stringsFlag{"app", CategoryClaim}
+			},
+			wantComposite: []string{"infra", CategoryComposite},
+			wantClaim:     []string{"app", CategoryClaim},
+		},
+		"Annotations": {
+			reason:        "Categories annotated for one kind of CRD should not leak onto the other.",
+			xrd:           annotated,
+			cfg:           func(cfg *config) { cfg.claimCategories = stringsFlag{"apps"} },
+			wantComposite: []string{"infra", CategoryComposite},
+			wantClaim:     []string{"team", "apps", CategoryClaim},
+		},
+		"AnnotationsDeduplicated": {
+			reason:        "Annotated categories should be trimmed, and empty or repeated ones dropped.",
+			xrd:           messy,
+			wantComposite: []string{CategoryComposite},
+			wantClaim:     []string{"team", CategoryClaim},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			if tc.cfg != nil {
+				tc.cfg(cfg)
+			}
+			crds := deriveCRDs(t, tc.xrd, cfg)
+			got := [][]string{crds[0].Spec.Names.Categories, crds[1].Spec.Names.Categories}
+			if diff := cmp.Diff([][]string{tc.wantComposite, tc.wantClaim}, got); diff != "" {
+				t.Errorf("\n%s\ncategories: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// minCELKubeVersion is the first Kubernetes version that enables CEL
+// validation rules (x-kubernetes-validations) by default.
+const minCELKubeVersion = "1.25"
+
+const (
+	errFmtParseKubeVersion = "cannot parse Kubernetes version %q"
+	errFmtCELUnsupported   = "CEL validation rules require --min-kube-version %s or later"
+)
+
+// ExclusiveCompositionRefsRule returns a CEL validation rule that forbids
+// setting both the compositionRef and compositionSelector of a spec.
+// Crossplane otherwise only rejects such a resource at runtime.
+func ExclusiveCompositionRefsRule() extv1.ValidationRule {
+	return extv1.ValidationRule{
+		Rule:    "!(has(self.compositionRef) && has(self.compositionSelector))",
+		Message: "at most one of compositionRef and compositionSelector may be set",
+	}
+}
+
+// EnforcedCompositionRefRule returns a CEL validation rule for a
+// compositionRef that only accepts the named Composition, which an XRD
+// enforces.
+func EnforcedCompositionRefRule(name string) extv1.ValidationRule {
+	return extv1.ValidationRule{
+		Rule:    "self.name == " + strconv.Quote(name),
+		Message: fmt.Sprintf("compositionRef must name the enforced Composition %q", name),
+	}
+}
+
+// checkCELSupported returns an error unless the supplied minimum Kubernetes
+// version enables CEL validation rules by default.
+func checkCELSupported(minKubeVersion string) error {
+	if minKubeVersion == "" {
+		return errors.Errorf(errFmtCELUnsupported, minCELKubeVersion)
+	}
+	v, err := version.ParseGeneric(minKubeVersion)
+	if err != nil {
+		return errors.Wrapf(err, errFmtParseKubeVersion, minKubeVersion)
+	}
+	if !v.AtLeast(version.MustParseGeneric(minCELKubeVersion)) {
+		return errors.Errorf(errFmtCELUnsupported, minCELKubeVersion)
+	}
+	return nil
+}
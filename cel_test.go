@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestExclusiveCompositionRefs(t *testing.T) {
+	cases := map[string]struct {
+		reason    string
+		exclusive bool
+		want      extv1.ValidationRules
+	}{
+		"Default": {
+			reason: "No rule should be added to the spec by default.",
+		},
+		"Exclusive": {
+			reason:    "A rule forbidding both compositionRef and compositionSelector should be added to the spec.",
+			exclusive: true,
+			want:      extv1.ValidationRules{ExclusiveCompositionRefsRule()},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.exclusiveRefs = tc.exclusive
+			cfg.minKubeVersion = "1.25"
+			for _, crd := range deriveCRDs(t, claimXRD, cfg) {
+				got := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].XValidations
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Errorf("\n%s\n%s: -want, +got spec validation rules:\n%s", tc.reason, crd.GetName(), diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCELSupported(t *testing.T) {
+	cases := map[string]struct {
+		reason         string
+		minKubeVersion string
+		wantErr        bool
+	}{
+		"Unset": {
+			reason:  "CEL rules should not be assumed to be supported without a minimum version.",
+			wantErr: true,
+		},
+		"TooOld": {
+			reason:         "Versions before CEL rules were enabled by default should be rejected.",
+			minKubeVersion: "1.24",
+			wantErr:        true,
+		},
+		"Supported": {
+			reason:         "The first version enabling CEL rules by default should be accepted.",
+			minKubeVersion: "1.25",
+		},
+		"Newer": {
+			reason:         "Later versions, with a leading v, should be accepted.",
+			minKubeVersion: "v1.28.3",
+		},
+		"Invalid": {
+			reason:         "A version that cannot be parsed should be rejected.",
+			minKubeVersion: "latest",
+			wantErr:        true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkCELSupported(tc.minKubeVersion)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("\n%s\ncheckCELSupported(%q): got error %v, want error %t", tc.reason, tc.minKubeVersion, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFmtInvalidDefinitions = "%d of %d definitions are invalid"
+)
+
+// validateDefinitions checks the definitions at the supplied paths without
+// generating anything, and writes their problems to w. It returns an error if
+// any definition has a problem. Each path may be a definition file, a
+// directory whose subdirectories hold definitions, or a directory followed by
+// /... to search it at any depth. Without paths the input directory is
+// checked.
+func validateDefinitions(ctx context.Context, w io.Writer, args []string, cfg *config) error {
+	paths, err := definitionPaths(ctx, args, cfg)
+	if err != nil {
+		return err
+	}
+
+	total, invalid := 0, 0
+	for _, p := range paths {
+		xrds, err := loadXrds(p)
+		if err != nil {
+			fmt.Fprintf(w, "%s: %s\n", p, err)
+			total++
+			invalid++
+			continue
+		}
+		for _, xrd := range xrds {
+			problems := definitionProblems(xrd.CompositeResourceDefinition)
+			for _, pr := range problems {
+				fmt.Fprintf(w, "%s: %s: %s\n", p, xrd.GetName(), pr)
+			}
+			total++
+			if len(problems) > 0 {
+				invalid++
+			}
+		}
+	}
+	if invalid > 0 {
+		return errors.Errorf(errFmtInvalidDefinitions, invalid, total)
+	}
+	fmt.Fprintf(w, "%d definitions are valid\n", total)
+	return nil
+}
+
+// definitionPaths returns the paths of the definitions named by the supplied
+// arguments. See validateDefinitions.
+func definitionPaths(ctx context.Context, args []string, cfg *config) ([]string, error) {
+	if len(args) == 0 {
+		return findPathsForPatterns(ctx, cfg.patterns, cfg.input, cfg.recursive)
+	}
+	var paths []string
+	for _, a := range args {
+		dir, recursive := a, cfg.recursive
+		if strings.HasSuffix(a, "/...") {
+			dir, recursive = strings.TrimSuffix(a, "/..."), true
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtStat, dir)
+		}
+		if !fi.IsDir() {
+			paths = append(paths, dir)
+			continue
+		}
+		found, err := findPathsForPatterns(ctx, cfg.patterns, dir, recursive)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, found...)
+	}
+	return dedupe(paths), nil
+}
+
+// definitionProblems returns the problems of the supplied definition that
+// would make its conversion fail or its CRDs unusable.
+func definitionProblems(xrd *v1.CompositeResourceDefinition) []string {
+	var problems []string
+	if xrd.Spec.ClaimNames != nil {
+		if err := validateClaimNames(xrd); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(xrd.Spec.Versions) == 0 {
+		return append(problems, "it has no versions")
+	}
+	referenceable, served := 0, 0
+	for _, vr := range xrd.Spec.Versions {
+		if vr.Referenceable {
+			referenceable++
+		}
+		if vr.Served {
+			served++
+		}
+		for _, field := range []string{"spec", "status"} {
+			if _, err := getSchema(field, vr.Schema); err != nil {
+				problems = append(problems, fmt.Sprintf("version %s: %s", vr.Name, err))
+			}
+		}
+	}
+	if referenceable != 1 {
+		problems = append(problems, fmt.Sprintf("exactly one version must be referenceable, but %d are", referenceable))
+	}
+	if served == 0 {
+		problems = append(problems, "no version is served")
+	}
+	return problems
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	errCommentDescriptions = "cannot render descriptions as comments"
+)
+
+// commentDescriptions re-renders a YAML document so that every schema property
+// with a description carries it as a line comment on its key. This makes the
+// documentation of a generated CRD readable at a glance.
+func commentDescriptions(y []byte) ([]byte, error) {
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(y, doc); err != nil {
+		return nil, errors.Wrap(err, errCommentDescriptions)
+	}
+	commentProperties(doc)
+
+	buf := &bytes.Buffer{}
+	enc := yamlv3.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, errors.Wrap(err, errCommentDescriptions)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.Wrap(err, errCommentDescriptions)
+	}
+	return buf.Bytes(), nil
+}
+
+// commentProperties walks the supplied node, adding the description of each
+// schema property as a line comment on the property's key.
+func commentProperties(n *yamlv3.Node) {
+	if n.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == "properties" && v.Kind == yamlv3.MappingNode {
+				for j := 0; j+1 < len(v.Content); j += 2 {
+					if d := mappingValue(v.Content[j+1], "description"); d != "" {
+						v.Content[j].LineComment = strings.Join(strings.Fields(d), " ")
+					}
+				}
+			}
+		}
+	}
+	for _, c := range n.Content {
+		commentProperties(c)
+	}
+}
+
+// mappingValue returns the scalar value of the supplied key of a mapping node,
+// or an empty string if there is none.
+func mappingValue(n *yamlv3.Node, key string) string {
+	if n.Kind != yamlv3.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key && n.Content[i+1].Kind == yamlv3.ScalarNode {
+			return n.Content[i+1].Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommentDescriptions(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		y      string
+		want   []string
+		absent []string
+	}{
+		"Described": {
+			reason: "A described property should carry its description as a comment on its key.",
+			y: `properties:
+  size:
+    type: string
+    description: The size.
+`,
+			want: []string{"size: # The size."},
+		},
+		"MultiLine": {
+			reason: "A multi-line description should be collapsed onto one line.",
+			y: `properties:
+  size:
+    type: string
+    description: |
+      The size
+      of the thing.
+`,
+			want: []string{"size: # The size of the thing."},
+		},
+		"Nested": {
+			reason: "Nested properties should be commented too.",
+			y: `properties:
+  spec:
+    type: object
+    properties:
+      size:
+        type: string
+        description: The size.
+`,
+			want:   []string{"  size: # The size."},
+			absent: []string{"spec: #"},
+		},
+		"Undescribed": {
+			reason: "A property without a description should carry no comment.",
+			y: `properties:
+  size:
+    type: string
+`,
+			absent: []string{"#"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			y, err := commentDescriptions([]byte(tc.y))
+			if err != nil {
+				t.Fatalf("\n%s\ncommentDescriptions(...): %v", tc.reason, err)
+			}
+			got := string(y)
+			for _, w := range tc.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("\n%s\ncommentDescriptions(...): output lacks %q:\n%s", tc.reason, w, got)
+				}
+			}
+			for _, a := range tc.absent {
+				if strings.Contains(got, a) {
+					t.Errorf("\n%s\ncommentDescriptions(...): output holds %q:\n%s", tc.reason, a, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// WebhookConversion returns a conversion that calls the supplied path of the
+// namespace/name service to convert between the versions of a CRD.
+func WebhookConversion(svc *webhookService) extv1.CustomResourceConversion {
+	path := svc.Path
+	return extv1.CustomResourceConversion{
+		Strategy: extv1.WebhookConverter,
+		Webhook: &extv1.WebhookConversion{
+			ClientConfig: &extv1.WebhookClientConfig{
+				Service: &extv1.ServiceReference{
+					Namespace: svc.Namespace,
+					Name:      svc.Name,
+					Path:      &path,
+				},
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+}
+
+// conversionFor returns the conversion of a derived CRD. Without a conversion
+// option it is explicitly None, which is what the API server would default it
+// to.
+func conversionFor(o *options) *extv1.CustomResourceConversion {
+	if o.conversion != nil {
+		return o.conversion.DeepCopy()
+	}
+	return &extv1.CustomResourceConversion{Strategy: extv1.NoneConverter}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// EnvCompositeDeletePolicy is the environment variable that sets the default
+// compositeDeletePolicy of generated claim CRDs when no flag does.
+const EnvCompositeDeletePolicy = "XRDCONVERT_COMPOSITE_DELETE_POLICY"
+
+const (
+	errReadConfigFile         = "cannot read config file"
+	errParseConfigFile        = "cannot parse config file"
+	errFmtUnknownDeletePolicy = "unknown composite delete policy %q: must be Background or Foreground"
+)
+
+// A fileConfig holds the defaults read from a config file.
+type fileConfig struct {
+	CompositeDeletePolicy string `json:"compositeDeletePolicy,omitempty"`
+}
+
+// loadFileConfig reads the config file at the supplied path. An empty path
+// returns an empty config.
+func loadFileConfig(path string) (*fileConfig, error) {
+	fc := &fileConfig{}
+	if path == "" {
+		return fc, nil
+	}
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadConfigFile)
+	}
+	if err := yaml.Unmarshal(y, fc); err != nil {
+		return nil, errors.Wrap(err, errParseConfigFile)
+	}
+	return fc, nil
+}
+
+// resolveCompositeDeletePolicy returns the default compositeDeletePolicy of
+// generated claim CRDs. The flag takes precedence over the environment, which
+// takes precedence over the config file. An empty result keeps the built-in
+// default.
+func resolveCompositeDeletePolicy(flagValue string, fc *fileConfig) (string, error) {
+	p := fc.CompositeDeletePolicy
+	if v, ok := os.LookupEnv(EnvCompositeDeletePolicy); ok && v != "" {
+		p = v
+	}
+	if flagValue != "" {
+		p = flagValue
+	}
+	switch p {
+	case "", "Background", "Foreground":
+		return p, nil
+	}
+	return "", errors.Errorf(errFmtUnknownDeletePolicy, p)
+}
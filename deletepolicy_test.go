@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestResolveCompositeDeletePolicy(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		flag    string
+		env     string
+		config  string
+		want    string
+		wantErr bool
+	}{
+		"BuiltIn": {
+			reason: "Without a flag, environment variable or config file the built-in default should be kept.",
+			want:   `"Background"`,
+		},
+		"Config": {
+			reason: "The config file should set the default.",
+			config: "compositeDeletePolicy: Foreground\n",
+			want:   `"Foreground"`,
+		},
+		"Env": {
+			reason: "The environment variable should override the config file.",
+			env:    "Background",
+			config: "compositeDeletePolicy: Foreground\n",
+			want:   `"Background"`,
+		},
+		"Flag": {
+			reason: "The flag should override the environment variable and config file.",
+			flag:   "Foreground",
+			env:    "Background",
+			config: "compositeDeletePolicy: Background\n",
+			want:   `"Foreground"`,
+		},
+		"Unknown": {
+			reason:  "An unknown policy should be rejected, whatever its source.",
+			env:     "Orphan",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(EnvCompositeDeletePolicy, tc.env)
+			path := ""
+			if tc.config != "" {
+				path = writeFiles(t, t.TempDir(), [2]string{"config.yaml", tc.config})[0]
+			}
+			fc, err := loadFileConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := testConfig()
+			cfg.deletePolicy, err = resolveCompositeDeletePolicy(tc.flag, fc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nresolveCompositeDeletePolicy(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			claim := deriveCRDs(t, claimXRD, cfg)[1]
+			d := claim.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["compositeDeletePolicy"].Default
+			if got := string(d.Raw); got != tc.want {
+				t.Errorf("\n%s\n%s: compositeDeletePolicy default %s, want %s", tc.reason, claim.GetName(), got, tc.want)
+			}
+		})
+	}
+}
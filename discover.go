@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errFmtParseSince = "cannot parse %q as a date or RFC 3339 timestamp"
+	errFmtStat       = "cannot stat %q"
+	errFmtWalk       = "cannot search %q for definitions"
+)
+
+// parseSince parses a --since value, either a date such as 2024-01-01 or an
+// RFC 3339 timestamp.
+func parseSince(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf(errFmtParseSince, s)
+}
+
+// filterModifiedSince returns the subset of paths whose files were modified
+// after the supplied time, preserving their order.
+func filterModifiedSince(paths []string, since time.Time) ([]string, error) {
+	filtered := make([]string, 0, len(paths))
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtStat, p)
+		}
+		if fi.ModTime().After(since) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// findPathsRecursively returns the paths of files beneath root, at any depth,
+// whose names match the supplied pattern. Hidden directories are skipped, and
+// symlinked directories are not followed, so the walk always terminates.
+func findPathsRecursively(ctx context.Context, pattern string, root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ok, err := filepath.Match(pattern, d.Name())
+		if ok {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	return paths, errors.Wrapf(err, errFmtWalk, root)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSince(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		"Date": {
+			reason: "A date should be parsed as midnight UTC.",
+			s:      "2024-01-01",
+			want:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"Timestamp": {
+			reason: "An RFC 3339 timestamp should be parsed.",
+			s:      "2024-01-01T12:30:00Z",
+			want:   time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		},
+		"Invalid": {
+			reason:  "Anything else should be rejected.",
+			s:       "yesterday",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseSince(tc.s)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nparseSince(%q): got error %v, want error %t", tc.reason, tc.s, err, tc.wantErr)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("\n%s\nparseSince(%q): got %v, want %v", tc.reason, tc.s, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterModifiedSince(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mtimes := map[string]time.Time{
+		"old/xrd.yaml":   since.Add(-24 * time.Hour),
+		"new/xrd.yaml":   since.Add(24 * time.Hour),
+		"newer/xrd.yaml": since.Add(48 * time.Hour),
+	}
+
+	dir := t.TempDir()
+	var paths []string
+	for _, f := range []string{"old/xrd.yaml", "new/xrd.yaml", "newer/xrd.yaml"} {
+		p := writeFiles(t, dir, [2]string{f, baseXRD})[0]
+		if err := os.Chtimes(p, mtimes[f], mtimes[f]); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	cases := map[string]struct {
+		reason  string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{
+		"Newer": {
+			reason: "Only files modified after the time should be kept, in order.",
+			paths:  paths,
+			want:   []string{paths[1], paths[2]},
+		},
+		"Missing": {
+			reason:  "A file that cannot be statted should be reported.",
+			paths:   []string{filepath.Join(dir, "missing.yaml")},
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := filterModifiedSince(tc.paths, since)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nfilterModifiedSince(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nfilterModifiedSince(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	errFmtDocumentSeparator = "invalid YAML document separator %q"
+)
+
+// A document of a YAML stream.
+type document struct {
+	// y is the document, without the separator line before it.
+	y []byte
+
+	// line is the zero-based line of the stream the document starts on.
+	line int
+}
+
+// splitDocuments splits a YAML stream into its documents at --- separator
+// lines, skipping empty ones, as the API machinery's YAML reader does. Only a
+// comment may follow a separator on its line.
+func splitDocuments(r io.Reader) ([]document, error) {
+	br := bufio.NewReader(r)
+	var docs []document
+	cur := document{}
+	flush := func() {
+		if len(bytes.TrimSpace(cur.y)) > 0 {
+			docs = append(docs, cur)
+		}
+	}
+	for n := 0; ; n++ {
+		l, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if bytes.HasPrefix(l, []byte("---")) {
+			if rest := bytes.TrimSpace(l[3:]); len(rest) > 0 && rest[0] != '#' {
+				return nil, errors.Errorf(errFmtDocumentSeparator, bytes.TrimSpace(l))
+			}
+			flush()
+			cur = document{line: n + 1}
+		} else {
+			cur.y = append(cur.y, l...)
+		}
+		if err == io.EOF {
+			flush()
+			return docs, nil
+		}
+	}
+}
+
+// readDocuments splits a YAML stream into its documents, skipping empty ones.
+func readDocuments(r io.Reader) ([][]byte, error) {
+	split, err := splitDocuments(r)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([][]byte, len(split))
+	for i, d := range split {
+		docs[i] = d.y
+	}
+	return docs, nil
+}
+
+// decodeNodes decodes each document of a YAML stream, split as readDocuments
+// splits it, into a node. The lines of the nodes are those of the stream.
+func decodeNodes(y []byte) ([]*yamlv3.Node, error) {
+	split, err := splitDocuments(bytes.NewReader(y))
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]*yamlv3.Node, len(split))
+	for i, d := range split {
+		doc := &yamlv3.Node{}
+		if err := yamlv3.Unmarshal(d.y, doc); err != nil {
+			return nil, err
+		}
+		shiftLines(doc, d.line)
+		docs[i] = doc
+	}
+	return docs, nil
+}
+
+// shiftLines moves the supplied node, and every node beneath it, down by the
+// supplied number of lines.
+func shiftLines(n *yamlv3.Node, lines int) {
+	n.Line += lines
+	for _, c := range n.Content {
+		shiftLines(c, lines)
+	}
+}
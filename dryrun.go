@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Dry run modes.
+const (
+	DryRunSummary = "summary"
+	DryRunDiff    = "diff"
+)
+
+const (
+	errFmtUnknownDryRun = "unknown dry run mode %q: must be %s or %s"
+	errFmtDiff          = "cannot diff %q"
+)
+
+// dryRunFlag is the --dry-run command line flag. It may be given without a
+// value, which selects the summary mode.
+type dryRunFlag string
+
+func (f *dryRunFlag) String() string { return string(*f) }
+
+func (f *dryRunFlag) IsBoolFlag() bool { return true }
+
+func (f *dryRunFlag) Set(v string) error {
+	switch v {
+	case "true":
+		*f = DryRunSummary
+	case "false":
+		*f = ""
+	case DryRunSummary, DryRunDiff:
+		*f = dryRunFlag(v)
+	default:
+		return errors.Errorf(errFmtUnknownDryRun, v, DryRunSummary, DryRunDiff)
+	}
+	return nil
+}
+
+// dryRun reports how writing y to the supplied output file would change it,
+// and its size, without writing it. In diff mode the change is printed as a
+// unified diff.
+// It returns true if the file would change.
+func dryRun(output string, y []byte, mode dryRunFlag) (bool, error) {
+	current, err := ioutil.ReadFile(output)
+	if err != nil && !os.IsNotExist(err) {
+		return false, errors.Wrapf(err, errFmtDiff, output)
+	}
+	switch {
+	case os.IsNotExist(err):
+		fmt.Fprintf(messages, "%s would be created (%d bytes)\n", output, len(y))
+	case bytes.Equal(current, y):
+		fmt.Fprintf(messages, "%s is unchanged (%d bytes)\n", output, len(y))
+		return false, nil
+	default:
+		fmt.Fprintf(messages, "%s would change (%d to %d bytes)\n", output, len(current), len(y))
+	}
+
+	if mode != DryRunDiff || strings.HasSuffix(output, ".gz") {
+		return true, nil
+	}
+	d, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(current)),
+		B:        difflib.SplitLines(string(y)),
+		FromFile: output,
+		ToFile:   output,
+		Context:  3,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, errFmtDiff, output)
+	}
+	fmt.Fprint(messages, d)
+	return true, nil
+}
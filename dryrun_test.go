@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRunFlag(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		v       string
+		want    dryRunFlag
+		wantErr bool
+	}{
+		"Bare": {
+			reason: "A bare --dry-run should select the summary mode.",
+			v:      "true",
+			want:   DryRunSummary,
+		},
+		"Off": {
+			reason: "--dry-run=false should turn dry runs off.",
+			v:      "false",
+		},
+		"Diff": {
+			reason: "--dry-run=diff should select the diff mode.",
+			v:      "diff",
+			want:   DryRunDiff,
+		},
+		"Unknown": {
+			reason:  "An unknown mode should be rejected.",
+			v:       "patch",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got dryRunFlag
+			err := got.Set(tc.v)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nSet(%q): got error %v, want error %t", tc.reason, tc.v, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("\n%s\nSet(%q): got %q, want %q", tc.reason, tc.v, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDryRunDiff(t *testing.T) {
+	const stale = "stale\n"
+
+	cases := map[string]struct {
+		reason      string
+		xrd         string
+		existing    string
+		want        []string
+		wantChanges int
+		wantErr     bool
+	}{
+		"Unchanged": {
+			reason: "An up to date output should be reported unchanged, without a diff.",
+			xrd:    baseXRD,
+			want:   []string{"example.org_xthings.yaml is unchanged"},
+		},
+		"Changed": {
+			reason:      "A stale output should be reported with a diff, and counted as a change.",
+			xrd:         baseXRD,
+			existing:    stale,
+			want:        []string{"example.org_xthings.yaml would change", "-stale", "+kind: CustomResourceDefinition"},
+			wantChanges: 1,
+		},
+		"Invalid": {
+			reason:  "An invalid definition should fail the run.",
+			xrd:     "kind: [broken",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := writeFiles(t, dir, [2]string{"a/xrd.yaml", tc.xrd})[0]
+			crds := filepath.Join(dir, "crds")
+			output := filepath.Join(crds, "example.org_xthings.yaml")
+			if !tc.wantErr {
+				if err := generateCrdForPaths(context.Background(), []string{m}, crds, testConfig()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.existing != "" {
+				writeFiles(t, crds, [2]string{"example.org_xthings.yaml", tc.existing})
+			}
+			before, _ := ioutil.ReadFile(output)
+
+			defer func(w io.Writer) { messages = w }(messages)
+			buf := &bytes.Buffer{}
+			messages = buf
+
+			cfg := testConfig()
+			cfg.dryRun = DryRunDiff
+			err := generateCrdForPaths(context.Background(), []string{m}, crds, cfg)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\ngenerateCrdForPaths(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(buf.String(), w) {
+					t.Errorf("\n%s\ngenerateCrdForPaths(...): output lacks %q:\n%s", tc.reason, w, buf.String())
+				}
+			}
+			if cfg.dryRunChanges != tc.wantChanges {
+				t.Errorf("\n%s\ngenerateCrdForPaths(...): %d changes, want %d", tc.reason, cfg.dryRunChanges, tc.wantChanges)
+			}
+			if after, _ := ioutil.ReadFile(output); !bytes.Equal(before, after) {
+				t.Errorf("\n%s\ngenerateCrdForPaths(...): a dry run wrote %s", tc.reason, output)
+			}
+		})
+	}
+}
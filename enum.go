@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	errReadEnumMapping  = "cannot read enum mapping"
+	errParseEnumMapping = "cannot parse enum mapping"
+)
+
+// loadEnumMapping reads a map of old to new enum values.
+func loadEnumMapping(path string) (map[string]string, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadEnumMapping)
+	}
+	m := map[string]string{}
+	if err := yaml.Unmarshal(y, &m); err != nil {
+		return nil, errors.Wrap(err, errParseEnumMapping)
+	}
+	return m, nil
+}
+
+// remapEnums replaces the string enum values of the supplied properties, and
+// of their nested properties and items, according to the supplied mapping.
+// Defaults of fields with an enum are remapped too, so they remain valid.
+func remapEnums(props map[string]extv1.JSONSchemaProps, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	for k, s := range props {
+		remapEnum(&s, m)
+		props[k] = s
+	}
+}
+
+func remapEnum(s *extv1.JSONSchemaProps, m map[string]string) {
+	if len(s.Enum) > 0 {
+		for i := range s.Enum {
+			s.Enum[i] = remapJSON(s.Enum[i], m)
+		}
+		if s.Default != nil {
+			d := remapJSON(*s.Default, m)
+			s.Default = &d
+		}
+	}
+	remapEnums(s.Properties, m)
+	if s.Items != nil && s.Items.Schema != nil {
+		remapEnum(s.Items.Schema, m)
+		// A default of an array field may hold values of its items' enum.
+		if len(s.Items.Schema.Enum) > 0 && s.Default != nil {
+			d := remapJSON(*s.Default, m)
+			s.Default = &d
+		}
+	}
+}
+
+// remapJSON returns the supplied JSON value with a string, or each string of
+// an array, replaced according to the supplied mapping.
+func remapJSON(j extv1.JSON, m map[string]string) extv1.JSON {
+	var str string
+	if err := json.Unmarshal(j.Raw, &str); err == nil {
+		if n, ok := m[str]; ok {
+			raw, _ := json.Marshal(n)
+			return extv1.JSON{Raw: raw}
+		}
+		return j
+	}
+	var strs []string
+	if err := json.Unmarshal(j.Raw, &strs); err == nil {
+		for i, v := range strs {
+			if n, ok := m[v]; ok {
+				strs[i] = n
+			}
+		}
+		raw, _ := json.Marshal(strs)
+		return extv1.JSON{Raw: raw}
+	}
+	return j
+}
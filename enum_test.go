@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestRemapEnums(t *testing.T) {
+	enums := baseXRD + `              size:
+                type: string
+                enum: [Small, Large]
+                default: Small
+              zones:
+                type: array
+                items:
+                  type: string
+                  enum: [EU, US]
+                default: [EU, US]
+              nested:
+                type: object
+                properties:
+                  tier:
+                    type: string
+                    enum: [Gold, Silver]
+                    default: Gold
+`
+	mapping := map[string]string{"Small": "small", "Large": "large", "EU": "eu", "Gold": "gold"}
+
+	// values returns the enum values and default of the supplied field.
+	values := func(t *testing.T, s extv1.JSONSchemaProps) []interface{} {
+		t.Helper()
+		var got []interface{}
+		for _, j := range append(append([]extv1.JSON{}, s.Enum...), *s.Default) {
+			var v interface{}
+			if err := json.Unmarshal(j.Raw, &v); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+		return got
+	}
+
+	cases := map[string]struct {
+		reason  string
+		mapping map[string]string
+		want    map[string][]interface{}
+	}{
+		"NoMapping": {
+			reason: "Enums should be kept as declared without a mapping.",
+			want: map[string][]interface{}{
+				"size":        {"Small", "Large", "Small"},
+				"zones":       {"EU", "US", []interface{}{"EU", "US"}},
+				"nested.tier": {"Gold", "Silver", "Gold"},
+			},
+		},
+		"Mapping": {
+			reason:  "Enum values and the defaults that reference them should be remapped consistently, leaving unmapped values alone.",
+			mapping: mapping,
+			want: map[string][]interface{}{
+				"size":        {"small", "large", "small"},
+				"zones":       {"eu", "US", []interface{}{"eu", "US"}},
+				"nested.tier": {"gold", "Silver", "gold"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.enumMapping = tc.mapping
+			spec := deriveCRDs(t, enums, cfg)[0].Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			zones := spec.Properties["zones"]
+			items := *zones.Items.Schema
+			items.Default = zones.Default
+			got := map[string][]interface{}{
+				"size":        values(t, spec.Properties["size"]),
+				"zones":       values(t, items),
+				"nested.tier": values(t, spec.Properties["nested"].Properties["tier"]),
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\n-want, +got enum values and default:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
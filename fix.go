@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	errFmtFixDefinition = "cannot fix definition %q"
+)
+
+// fixFieldDescriptions adds a TODO description to every field beneath the
+// spec and status of each version of each definition at the supplied path that
+// has none. Other documents in the file are kept as they are. The original file
+// is first copied to a .bak file alongside it. It returns the number of
+// descriptions added.
+func fixFieldDescriptions(path string) (int, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, errFmtFixDefinition, path)
+	}
+	docs, err := splitDocuments(bytes.NewReader(y))
+	if err != nil {
+		return 0, errors.Wrapf(err, errFmtFixDefinition, path)
+	}
+
+	fixed := 0
+	nodes := make([]*yamlv3.Node, len(docs))
+	for i, d := range docs {
+		doc := &yamlv3.Node{}
+		if err := yamlv3.Unmarshal(d.y, doc); err != nil {
+			return 0, errors.Wrapf(err, errFmtFixDefinition, path)
+		}
+		if len(doc.Content) == 0 || mappingValue(doc.Content[0], "kind") != v1.CompositeResourceDefinitionKind {
+			continue
+		}
+		versions := mappingNode(doc, "spec", "versions")
+		if versions == nil || versions.Kind != yamlv3.SequenceNode {
+			continue
+		}
+		added := 0
+		for _, v := range versions.Content {
+			for _, f := range []string{"spec", "status"} {
+				added += addDescriptions(mappingNode(v, "schema", "openAPIV3Schema", "properties", f, "properties"))
+			}
+		}
+		if added > 0 {
+			nodes[i] = doc
+			fixed += added
+		}
+	}
+	if fixed == 0 {
+		return 0, nil
+	}
+
+	buf := &bytes.Buffer{}
+	for i, d := range docs {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		if nodes[i] == nil {
+			buf.Write(d.y)
+			continue
+		}
+		enc := yamlv3.NewEncoder(buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(nodes[i]); err != nil {
+			return 0, errors.Wrapf(err, errFmtFixDefinition, path)
+		}
+		if err := enc.Close(); err != nil {
+			return 0, errors.Wrapf(err, errFmtFixDefinition, path)
+		}
+	}
+	if err := ioutil.WriteFile(path+".bak", y, 0644); err != nil {
+		return 0, errors.Wrapf(err, errFmtFixDefinition, path)
+	}
+	return fixed, errors.Wrapf(ioutil.WriteFile(path, buf.Bytes(), 0644), errFmtFixDefinition, path)
+}
+
+// addDescriptions adds a TODO description to each of the supplied properties,
+// and their nested properties, that has none. It returns the number added.
+func addDescriptions(props *yamlv3.Node) int {
+	if props == nil || props.Kind != yamlv3.MappingNode {
+		return 0
+	}
+	added := 0
+	for i := 0; i+1 < len(props.Content); i += 2 {
+		name, s := props.Content[i], props.Content[i+1]
+		if s.Kind != yamlv3.MappingNode {
+			continue
+		}
+		if mappingNode(s, "description") == nil {
+			s.Content = append([]*yamlv3.Node{
+				{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: "description"},
+				{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: fmt.Sprintf("TODO: describe %s.", name.Value)},
+			}, s.Content...)
+			added++
+		}
+		added += addDescriptions(mappingNode(s, "properties"))
+		added += addDescriptions(mappingNode(s, "items", "properties"))
+	}
+	return added
+}
+
+// mappingNode returns the value at the supplied path of keys beneath the
+// supplied node, or nil if there is none. Document nodes are looked through.
+func mappingNode(n *yamlv3.Node, path ...string) *yamlv3.Node {
+	for _, key := range path {
+		if n.Kind == yamlv3.DocumentNode && len(n.Content) == 1 {
+			n = n.Content[0]
+		}
+		if n.Kind != yamlv3.MappingNode {
+			return nil
+		}
+		var next *yamlv3.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
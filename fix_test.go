@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFixFieldDescriptions(t *testing.T) {
+	configMap := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: untouched
+data:
+  spec: value
+`
+	described := strings.Replace(baseXRD, "              base:\n", "              base:\n                description: The base.\n", 1)
+
+	cases := map[string]struct {
+		reason string
+		in     string
+		want   int
+		docs   int
+		keep   string
+	}{
+		"SingleDocument": {
+			reason: "Fields without a description should be given a TODO one.",
+			in:     baseXRD,
+			want:   1,
+			docs:   1,
+		},
+		"Described": {
+			reason: "Fields with a description should be left alone.",
+			in:     described,
+			want:   0,
+			docs:   1,
+		},
+		"MultipleDocuments": {
+			reason: "Every definition in a file should be fixed, and every document kept.",
+			in:     baseXRD + "---\n" + configMap + "---\n" + overlayXRD,
+			want:   2,
+			docs:   3,
+			keep:   "spec: value",
+		},
+		"LeadingComment": {
+			reason: "A comment-only document should be kept as it is.",
+			in:     "# Things.\n---\n" + baseXRD,
+			want:   1,
+			docs:   2,
+			keep:   "# Things.\n---\n",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := writeFiles(t, t.TempDir(), [2]string{"xrd.yaml", tc.in})[0]
+			n, err := fixFieldDescriptions(p)
+			if err != nil {
+				t.Fatalf("\n%s\nfixFieldDescriptions(...): %v", tc.reason, err)
+			}
+			if n != tc.want {
+				t.Errorf("\n%s\nfixFieldDescriptions(...): added %d descriptions, want %d", tc.reason, n, tc.want)
+			}
+
+			y, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs, err := readDocuments(strings.NewReader(string(y)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(docs) != tc.docs {
+				t.Errorf("\n%s\nfixFieldDescriptions(...): file holds %d documents, want %d:\n%s", tc.reason, len(docs), tc.docs, y)
+			}
+			if got := strings.Count(string(y), "TODO: describe"); got != tc.want {
+				t.Errorf("\n%s\nfixFieldDescriptions(...): file holds %d TODO descriptions, want %d:\n%s", tc.reason, got, tc.want, y)
+			}
+			if !strings.Contains(string(y), tc.keep) {
+				t.Errorf("\n%s\nfixFieldDescriptions(...): other documents were changed:\n%s", tc.reason, y)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	errFmtInvalidKeyValue = "%q is not a key=value pair"
+	errFmtInvalidLabel    = "invalid label %q: %s"
+)
+
+// stringsFlag is a repeatable command line flag. Each occurrence may hold a
+// comma separated list of values.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*f = append(*f, s)
+		}
+	}
+	return nil
+}
+
+// mapFlag is a repeatable command line flag of key=value pairs. Each occurrence
+// may hold a comma separated list of pairs.
+type mapFlag map[string]string
+
+func (f *mapFlag) String() string {
+	pairs := make([]string, 0, len(*f))
+	for k, v := range *f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f *mapFlag) Set(v string) error {
+	if *f == nil {
+		*f = mapFlag{}
+	}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			return errors.Errorf(errFmtInvalidKeyValue, s)
+		}
+		(*f)[k] = v
+	}
+	return nil
+}
+
+// checkLabels returns an error if any of the supplied labels has a key or
+// value that Kubernetes would reject.
+func checkLabels(l map[string]string) error {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		errs := append(validation.IsQualifiedName(k), validation.IsValidLabelValue(l[k])...)
+		if len(errs) > 0 {
+			return errors.Errorf(errFmtInvalidLabel, k+"="+l[k], strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
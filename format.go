@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// Formats of generated CRDs.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+const (
+	errFmtUnknownFormat        = "unknown format %q: must be %s or %s"
+	errCommentDescriptionsJSON = "--comment-descriptions requires the yaml format"
+	errSingleFileJSON          = "--single-file requires the yaml format"
+)
+
+// checkFormat returns an error if the supplied format is not supported.
+func checkFormat(format string) error {
+	switch format {
+	case FormatYAML, FormatJSON:
+		return nil
+	}
+	return errors.Errorf(errFmtUnknownFormat, format, FormatYAML, FormatJSON)
+}
+
+// checkFormatOptions returns an error if the configured format cannot be used
+// with the other configured output options. JSON has neither comments nor a
+// document separator, so CRDs cannot share a JSON file.
+func checkFormatOptions(cfg *config) error {
+	if cfg.format != FormatJSON {
+		return nil
+	}
+	switch {
+	case cfg.commentDescriptions:
+		return errors.New(errCommentDescriptionsJSON)
+	case cfg.singleFile:
+		return errors.New(errSingleFileJSON)
+	}
+	return nil
+}
+
+// encodeCRD encodes the supplied CRD in the supplied format.
+func encodeCRD(crd *extv1.CustomResourceDefinition, format string) ([]byte, error) {
+	if format != FormatJSON {
+		buf := &bytes.Buffer{}
+		err := writeCRD(buf, crd)
+		return buf.Bytes(), err
+	}
+	j, err := json.MarshalIndent(crd, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtWriteCRD, crd.GetName())
+	}
+	return append(j, '\n'), nil
+}
+
+// writeDocumentIn writes the supplied encoded CRD to w as a document of a
+// stream in the supplied format. JSON documents need no separator.
+func writeDocumentIn(w io.Writer, y []byte, format string) error {
+	if format != FormatJSON {
+		return writeDocument(w, y)
+	}
+	_, err := w.Write(y)
+	return err
+}
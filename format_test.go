@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFormatOptions(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		cfg     config
+		wantErr string
+	}{
+		"YAML": {
+			reason: "Every output option should be usable with YAML.",
+			cfg:    config{format: FormatYAML, singleFile: true, commentDescriptions: true},
+		},
+		"JSON": {
+			reason: "JSON should be usable when each CRD is written to its own file.",
+			cfg:    config{format: FormatJSON},
+		},
+		"JSONCommentDescriptions": {
+			reason:  "JSON cannot hold comments.",
+			cfg:     config{format: FormatJSON, commentDescriptions: true},
+			wantErr: errCommentDescriptionsJSON,
+		},
+		"JSONSingleFile": {
+			reason:  "CRDs cannot share a JSON file.",
+			cfg:     config{format: FormatJSON, singleFile: true},
+			wantErr: errSingleFileJSON,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkFormatOptions(&tc.cfg)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("\n%s\ncheckFormatOptions(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("\n%s\ncheckFormatOptions(...): got error %v, want %q", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
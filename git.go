@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errFmtGitDiff    = "cannot diff against git revision %q"
+	errListUntracked = "cannot list untracked files"
+)
+
+// runGit runs git with the supplied arguments in dir and returns its standard
+// output. It is a variable so the git invocation can be replaced.
+var runGit = func(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// changedFiles returns the absolute paths of files under dir that differ from
+// the supplied git revision, along with untracked files that are not ignored.
+// A revision such as origin/main...HEAD diffs against the merge base of the
+// two, so only changes made on HEAD's branch count. The returned bool is false
+// when dir is not inside a git work tree, in which case callers should fall
+// back to converting everything.
+func changedFiles(ctx context.Context, dir, base string) (map[string]bool, bool, error) {
+	if _, err := runGit(ctx, dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		if err := checkContext(ctx); err != nil {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+
+	out, err := runGit(ctx, dir, "diff", "--name-only", "--relative", base)
+	if err := checkContext(ctx); err != nil {
+		return nil, true, err
+	}
+	if err != nil {
+		return nil, true, errors.Wrapf(err, errFmtGitDiff, base)
+	}
+
+	untracked, err := runGit(ctx, dir, "ls-files", "--others", "--exclude-standard")
+	if err := checkContext(ctx); err != nil {
+		return nil, true, err
+	}
+	if err != nil {
+		return nil, true, errors.Wrap(err, errListUntracked)
+	}
+
+	changed := map[string]bool{}
+	for _, l := range strings.Split(string(out)+string(untracked), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		changed[filepath.Join(dir, l)] = true
+	}
+	return changed, true, nil
+}
+
+// filterChanged returns the subset of paths present in changed, preserving
+// their order.
+func filterChanged(paths []string, changed map[string]bool) []string {
+	filtered := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if changed[filepath.Clean(p)] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
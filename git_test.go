@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestChangedFiles(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	cases := map[string]struct {
+		reason    string
+		revParse  error
+		diff      string
+		diffErr   error
+		untracked string
+		lsErr     error
+		want      []string
+		wantInGit bool
+		wantErr   bool
+	}{
+		"NotARepository": {
+			reason:   "Outside a git work tree every definition should be converted.",
+			revParse: errBoom,
+		},
+		"Changed": {
+			reason:    "Files git reports as changed should be returned beneath the directory.",
+			diff:      "a/xrd.yaml\n\nb/test.yaml\n",
+			want:      []string{"a/xrd.yaml", "b/test.yaml"},
+			wantInGit: true,
+		},
+		"Untracked": {
+			reason:    "Files git does not track yet should be returned along with changed ones.",
+			diff:      "a/xrd.yaml\n",
+			untracked: "c/xrd.yaml\n",
+			want:      []string{"a/xrd.yaml", "c/xrd.yaml"},
+			wantInGit: true,
+		},
+		"NoChanges": {
+			reason:    "No files should be returned when git reports none.",
+			want:      []string{},
+			wantInGit: true,
+		},
+		"DiffFails": {
+			reason:    "A failing git diff should be returned as an error.",
+			diffErr:   errBoom,
+			wantInGit: true,
+			wantErr:   true,
+		},
+		"ListUntrackedFails": {
+			reason:    "Failing to list untracked files should be returned as an error.",
+			lsErr:     errBoom,
+			wantInGit: true,
+			wantErr:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer func(f func(context.Context, string, ...string) ([]byte, error)) { runGit = f }(runGit)
+			runGit = func(_ context.Context, _ string, args ...string) ([]byte, error) {
+				switch args[0] {
+				case "rev-parse":
+					return nil, tc.revParse
+				case "ls-files":
+					if got := strings.Join(args, " "); got != "ls-files --others --exclude-standard" {
+						t.Errorf("runGit(...): got arguments %q", got)
+					}
+					return []byte(tc.untracked), tc.lsErr
+				}
+				if got := strings.Join(args, " "); got != "diff --name-only --relative main" {
+					t.Errorf("runGit(...): got arguments %q", got)
+				}
+				return []byte(tc.diff), tc.diffErr
+			}
+
+			dir := t.TempDir()
+			changed, inGit, err := changedFiles(context.Background(), dir, "main")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nchangedFiles(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			if inGit != tc.wantInGit {
+				t.Errorf("\n%s\nchangedFiles(...): got in git %t, want %t", tc.reason, inGit, tc.wantInGit)
+			}
+			if tc.want == nil {
+				if changed != nil {
+					t.Errorf("\n%s\nchangedFiles(...): got %v, want none", tc.reason, changed)
+				}
+				return
+			}
+			var got []string
+			for _, p := range filterChanged([]string{
+				filepath.Join(dir, "a", "xrd.yaml"),
+				filepath.Join(dir, "b", "test.yaml"),
+				filepath.Join(dir, "c", "xrd.yaml"),
+			}, changed) {
+				rel, _ := filepath.Rel(dir, p)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nchangedFiles(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
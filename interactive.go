@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+const (
+	errReadSelection     = "cannot read selection"
+	errNoSelection       = "no definitions were selected"
+	errFmtSelectionEntry = "invalid selection %q: must be a number or range between 1 and %d"
+)
+
+// selectPathsInteractively lists the supplied paths and asks which of them to
+// convert. All paths are selected without prompting when stdin is not a
+// terminal. The prompt is written to standard error, which keeps standard
+// output free for generated CRDs.
+func selectPathsInteractively(paths []string, log Logger) ([]string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		log.Infof("Not a terminal, converting all definitions")
+		return paths, nil
+	}
+	return promptForPaths(os.Stdin, os.Stderr, paths)
+}
+
+// promptForPaths writes a numbered list of the supplied paths to w and returns
+// those selected by the next line read from r, asking again if it is invalid.
+func promptForPaths(r io.Reader, w io.Writer, paths []string) ([]string, error) {
+	for i, p := range paths {
+		fmt.Fprintf(w, "%3d) %s\n", i+1, p)
+	}
+	s := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "Convert which definitions? (e.g. 1,3-5; empty for all): ")
+		if !s.Scan() {
+			if err := s.Err(); err != nil {
+				return nil, errors.Wrap(err, errReadSelection)
+			}
+			return nil, errors.New(errNoSelection)
+		}
+		selected, err := parseSelection(s.Text(), len(paths))
+		if err != nil {
+			fmt.Fprintln(w, err)
+			continue
+		}
+		return selectPaths(paths, selected), nil
+	}
+}
+
+// parseSelection parses a comma separated list of one-based indexes and
+// ranges of indexes, such as 1,3-5, into a set of zero-based indexes below n.
+// An empty selection, or "all", selects every index.
+func parseSelection(sel string, n int) (map[int]bool, error) {
+	selected := map[int]bool{}
+	if s := strings.TrimSpace(sel); s == "" || s == "all" {
+		for i := 0; i < n; i++ {
+			selected[i] = true
+		}
+		return selected, nil
+	}
+	for _, e := range strings.Split(sel, ",") {
+		e = strings.TrimSpace(e)
+		from, to, isRange := strings.Cut(e, "-")
+		if !isRange {
+			to = from
+		}
+		lo, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, errors.Errorf(errFmtSelectionEntry, e, n)
+		}
+		hi, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil || lo < 1 || hi > n || lo > hi {
+			return nil, errors.Errorf(errFmtSelectionEntry, e, n)
+		}
+		for i := lo; i <= hi; i++ {
+			selected[i-1] = true
+		}
+	}
+	return selected, nil
+}
+
+// selectPaths returns the supplied paths whose indexes are selected, in their
+// original order.
+func selectPaths(paths []string, selected map[int]bool) []string {
+	out := make([]string, 0, len(selected))
+	for i, p := range paths {
+		if selected[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
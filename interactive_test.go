@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPromptForPaths(t *testing.T) {
+	paths := []string{"a/xrd.yaml", "b/xrd.yaml", "c/xrd.yaml", "d/xrd.yaml"}
+
+	cases := map[string]struct {
+		reason  string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		"Empty": {
+			reason: "An empty selection should select every path.",
+			input:  "\n",
+			want:   paths,
+		},
+		"All": {
+			reason: "Selecting all should select every path.",
+			input:  "all\n",
+			want:   paths,
+		},
+		"Indexes": {
+			reason: "Indexes should select paths in their original order.",
+			input:  " 3 , 1\n",
+			want:   []string{"a/xrd.yaml", "c/xrd.yaml"},
+		},
+		"Range": {
+			reason: "Ranges and indexes should be combined without repeating paths.",
+			input:  "2-3,3,4\n",
+			want:   []string{"b/xrd.yaml", "c/xrd.yaml", "d/xrd.yaml"},
+		},
+		"InvalidThenValid": {
+			reason: "An invalid selection should be asked for again.",
+			input:  "5\n0\n3-2\nb\n2\n",
+			want:   []string{"b/xrd.yaml"},
+		},
+		"NoInput": {
+			reason:  "Running out of input without a valid selection should be an error.",
+			input:   "5\n",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := promptForPaths(strings.NewReader(tc.input), ioutil.Discard, paths)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\npromptForPaths(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\npromptForPaths(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSelectPathsNotATerminal(t *testing.T) {
+	paths := []string{"a/xrd.yaml", "b/xrd.yaml"}
+	dir := t.TempDir()
+	files := writeFiles(t, dir, [2]string{"stdin", "1\n"}, [2]string{"stdout", ""})
+
+	defer func(in, out *os.File) { os.Stdin, os.Stdout = in, out }(os.Stdin, os.Stdout)
+	in, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(files[1], os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	os.Stdin, os.Stdout = in, out
+
+	log := &bytes.Buffer{}
+	got, err := selectPathsInteractively(paths, NewLogger(log, true))
+	if err != nil {
+		t.Fatalf("selectPathsInteractively(...): %v", err)
+	}
+	if diff := cmp.Diff(paths, got); diff != "" {
+		t.Errorf("selectPathsInteractively(...): -want, +got:\n%s", diff)
+	}
+	if !strings.Contains(log.String(), "Not a terminal") {
+		t.Errorf("selectPathsInteractively(...): got log %q, want a notice that stdin is not a terminal", log.String())
+	}
+	if b, _ := ioutil.ReadFile(files[1]); len(b) > 0 {
+		t.Errorf("selectPathsInteractively(...): wrote %q to standard output", b)
+	}
+}
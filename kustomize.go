@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// KustomizationFile is the name of the kustomization --emit-kustomization
+// writes to the output directory.
+const KustomizationFile = "kustomization.yaml"
+
+const (
+	errKustomizationStdout   = "--emit-kustomization cannot be used with --stdout"
+	errKustomizationGzip     = "--emit-kustomization cannot be used with --gzip"
+	errFmtWriteKustomization = "cannot write kustomization %q"
+)
+
+// kustomization is a kustomize file that only lists resources.
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// writeKustomization writes a kustomization listing the supplied outputs, in
+// sorted order and relative to the output folder, to the output folder. It
+// replaces any existing kustomization, so that it lists exactly the CRDs
+// generated by this run.
+func writeKustomization(outputFolder string, outputs []string, cfg *config) error {
+	output := filepath.Join(outputFolder, KustomizationFile)
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{},
+	}
+	for _, o := range dedupe(outputs) {
+		rel, err := filepath.Rel(outputFolder, o)
+		if err != nil {
+			return errors.Wrapf(err, errFmtWriteKustomization, output)
+		}
+		k.Resources = append(k.Resources, filepath.ToSlash(rel))
+	}
+	sort.Strings(k.Resources)
+
+	y, err := yaml.Marshal(k)
+	if err != nil {
+		return errors.Wrapf(err, errFmtWriteKustomization, output)
+	}
+
+	if cfg.dryRun != "" {
+		changed, err := dryRun(output, y, cfg.dryRun)
+		if changed {
+			cfg.dryRunChanges++
+		}
+		return err
+	}
+	if unchanged(output, y) {
+		cfg.log.Infof("%s is unchanged", output)
+		return nil
+	}
+	if err := ioutil.WriteFile(output, y, 0644); err != nil {
+		return errors.Wrapf(err, errFmtWriteKustomization, output)
+	}
+	cfg.log.Infof("%s written", output)
+	return nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+const (
+	errFmtUnknownLintRule = "unknown lint rule or rule set %q"
+)
+
+// Lint rule sets.
+const (
+	LintSetAll    = "all"
+	LintSetDocs   = "docs"
+	LintSetSchema = "schema"
+	LintSetUX     = "ux"
+)
+
+// A lintFinding is a problem reported by a lint rule.
+type lintFinding struct {
+	Rule     string
+	Path     string
+	Document int
+	Field    string
+	Message  string
+}
+
+func (f lintFinding) String() string {
+	if f.Field != "" {
+		return fmt.Sprintf("%s: %s: %s [%s]", f.Path, f.Field, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s [%s]", f.Path, f.Message, f.Rule)
+}
+
+// A lintTarget is a definition file and the composite resource CRD generated
+// from it.
+type lintTarget struct {
+	path  string
+	index int
+	xrd   *v1.CompositeResourceDefinition
+	crd   *extv1.CustomResourceDefinition
+}
+
+// A lintRule checks a lint target for one kind of problem.
+type lintRule struct {
+	id    string
+	sets  []string
+	check func(t lintTarget) []lintFinding
+}
+
+var lintRules = []lintRule{
+	{id: "field-description", sets: []string{LintSetDocs}, check: lintFieldDescriptions},
+	{id: "enum-default", sets: []string{LintSetSchema}, check: lintEnumDefaults},
+	{id: "printer-columns", sets: []string{LintSetUX}, check: lintPrinterColumns},
+	{id: "version-order", sets: []string{LintSetSchema}, check: lintVersionOrder},
+	{id: "crd-size", sets: []string{LintSetSchema}, check: lintCRDSize},
+}
+
+// selectLintRules returns the lint rules named by the supplied rule IDs or rule
+// set names. All rules are selected if none are named.
+func selectLintRules(names []string) ([]lintRule, error) {
+	if len(names) == 0 {
+		return lintRules, nil
+	}
+	selected := map[string]bool{}
+	for _, n := range names {
+		found := false
+		for _, r := range lintRules {
+			if n == LintSetAll || n == r.id || contains(r.sets, n) {
+				selected[r.id] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.Errorf(errFmtUnknownLintRule, n)
+		}
+	}
+	var rules []lintRule
+	for _, r := range lintRules {
+		if selected[r.id] {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// lintDefinitions runs the selected lint rules over the definitions at the
+// supplied paths and prints their findings. With --fix, fields reported for
+// having no description are given a TODO description in their definition.
+func lintDefinitions(ctx context.Context, paths []string, cfg *config) error {
+	findings, err := lintPaths(ctx, paths, cfg)
+	if err != nil {
+		return err
+	}
+	fix := map[string]bool{}
+	for _, f := range findings {
+		fmt.Fprintf(messages, "Warning: %s\n", f)
+		cfg.sarif.addFinding(f)
+		if f.Rule == "field-description" {
+			fix[f.Path] = cfg.fix
+		}
+	}
+	for _, p := range paths {
+		if !fix[p] {
+			continue
+		}
+		n, err := fixFieldDescriptions(p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(messages, "Added %d TODO descriptions to %s; the original is in %s.bak\n", n, p, p)
+	}
+	return nil
+}
+
+// lintPaths runs the selected lint rules over the definitions at the supplied
+// paths and returns their findings.
+func lintPaths(ctx context.Context, paths []string, cfg *config) ([]lintFinding, error) {
+	rules, err := selectLintRules(cfg.lintRules)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []lintFinding
+	for _, p := range paths {
+		if err := checkContext(ctx); err != nil {
+			return nil, err
+		}
+		xrds, err := cfg.load(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtLoadXrd, p)
+		}
+		for _, xrd := range xrds {
+			crd, err := ForCompositeResource(xrd.CompositeResourceDefinition, xrd.compositeOptions(cfg.compositeOptions())...)
+			if err != nil {
+				return nil, err
+			}
+			t := lintTarget{path: p, index: xrd.index, xrd: xrd.CompositeResourceDefinition, crd: crd}
+			for _, r := range rules {
+				findings = append(findings, r.check(t)...)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// userSchema returns the parsed schema the supplied version declares, or nil
+// if it declares none or it cannot be parsed.
+func userSchema(vr v1.CompositeResourceDefinitionVersion) *extv1.JSONSchemaProps {
+	if vr.Schema == nil || len(vr.Schema.OpenAPIV3Schema.Raw) == 0 {
+		return nil
+	}
+	s := &extv1.JSONSchemaProps{}
+	if err := json.Unmarshal(vr.Schema.OpenAPIV3Schema.Raw, s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// walkUserFields calls fn for every field beneath the spec and status of each
+// version's schema, passing a field path such as v1alpha1:spec.parameters.
+func walkUserFields(xrd *v1.CompositeResourceDefinition, fn func(field string, s extv1.JSONSchemaProps)) {
+	var walk func(prefix string, props map[string]extv1.JSONSchemaProps)
+	walk = func(prefix string, props map[string]extv1.JSONSchemaProps) {
+		for _, k := range sortedKeys(props) {
+			s := props[k]
+			field := prefix + "." + k
+			fn(field, s)
+			walk(field, s.Properties)
+			if s.Items != nil && s.Items.Schema != nil {
+				walk(field+"[]", s.Items.Schema.Properties)
+			}
+		}
+	}
+	for _, vr := range xrd.Spec.Versions {
+		s := userSchema(vr)
+		if s == nil {
+			continue
+		}
+		for _, f := range []string{"spec", "status"} {
+			walk(vr.Name+":"+f, s.Properties[f].Properties)
+		}
+	}
+}
+
+func lintFieldDescriptions(t lintTarget) []lintFinding {
+	var findings []lintFinding
+	walkUserFields(t.xrd, func(field string, s extv1.JSONSchemaProps) {
+		if strings.TrimSpace(s.Description) == "" {
+			findings = append(findings, lintFinding{Rule: "field-description", Path: t.path, Document: t.index, Field: field, Message: "field has no description"})
+		}
+	})
+	return findings
+}
+
+func lintEnumDefaults(t lintTarget) []lintFinding {
+	var findings []lintFinding
+	walkUserFields(t.xrd, func(field string, s extv1.JSONSchemaProps) {
+		if len(s.Enum) > 0 && s.Default == nil {
+			findings = append(findings, lintFinding{Rule: "enum-default", Path: t.path, Document: t.index, Field: field, Message: "enum has no default"})
+		}
+	})
+	return findings
+}
+
+func lintPrinterColumns(t lintTarget) []lintFinding {
+	var findings []lintFinding
+	for _, vr := range t.xrd.Spec.Versions {
+		if len(vr.AdditionalPrinterColumns) == 0 {
+			findings = append(findings, lintFinding{Rule: "printer-columns", Path: t.path, Document: t.index, Field: vr.Name, Message: "version defines no printer columns"})
+		}
+	}
+	return findings
+}
+
+func lintVersionOrder(t lintTarget) []lintFinding {
+	var findings []lintFinding
+	vs := t.xrd.Spec.Versions
+	for i := 1; i < len(vs); i++ {
+		if version.CompareKubeAwareVersionStrings(vs[i-1].Name, vs[i].Name) > 0 {
+			findings = append(findings, lintFinding{Rule: "version-order", Path: t.path, Document: t.index, Message: fmt.Sprintf("version %s is listed after the newer version %s", vs[i].Name, vs[i-1].Name)})
+		}
+	}
+	return findings
+}
+
+func lintCRDSize(t lintTarget) []lintFinding {
+	y, err := yaml.Marshal(t.crd)
+	if err != nil || len(y) <= defaultSizeLimit {
+		return nil
+	}
+	return []lintFinding{{Rule: "crd-size", Path: t.path, Document: t.index, Message: fmt.Sprintf("generated CRD %s is %d bytes, exceeding %d bytes", t.crd.GetName(), len(y), defaultSizeLimit)}}
+}
+
+func sortedKeys(m map[string]extv1.JSONSchemaProps) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLintPaths(t *testing.T) {
+	described := strings.Replace(baseXRD, "                type: string\n", "                type: string\n                description: The base.\n", 1)
+	enum := described + `              size:
+                type: string
+                description: The size.
+                enum: [small, large]
+`
+	unordered := strings.Replace(described, "  - name: v1\n", "  - name: v2\n", 1) + `  - name: v1
+    served: true
+    referenceable: false
+    schema:
+      openAPIV3Schema:
+        type: object
+`
+
+	cases := map[string]struct {
+		reason  string
+		xrd     string
+		rules   []string
+		want    []string
+		wantDoc int
+		wantErr bool
+	}{
+		"FieldDescription": {
+			reason: "A field without a description should be reported.",
+			xrd:    baseXRD,
+			rules:  []string{"field-description"},
+			want:   []string{"field-description v1:spec.base"},
+		},
+		"EnumDefault": {
+			reason: "An enum without a default should be reported.",
+			xrd:    enum,
+			rules:  []string{LintSetSchema},
+			want:   []string{"enum-default v1:spec.size"},
+		},
+		"VersionOrder": {
+			reason: "A version listed after a newer one should be reported.",
+			xrd:    unordered,
+			rules:  []string{"version-order"},
+			want:   []string{"version-order "},
+		},
+		"LaterDocument": {
+			reason:  "A finding in a later document of a file should name that document.",
+			xrd:     described + "---\n" + strings.NewReplacer("XThing", "XOther", "xthings", "xothers").Replace(unordered),
+			rules:   []string{"version-order"},
+			want:    []string{"version-order "},
+			wantDoc: 1,
+		},
+		"RuleSet": {
+			reason: "Only the rules of the selected rule set should run.",
+			xrd:    baseXRD,
+			rules:  []string{LintSetUX},
+			want:   []string{"printer-columns v1"},
+		},
+		"UnknownRule": {
+			reason:  "An unknown rule or rule set should be rejected.",
+			xrd:     baseXRD,
+			rules:   []string{"no-such-rule"},
+			wantErr: true,
+		},
+		"Clean": {
+			reason: "A definition following every practice of a rule set should not be reported.",
+			xrd:    described,
+			rules:  []string{LintSetDocs},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.lintRules = tc.rules
+			findings, err := lintPaths(context.Background(), writeFiles(t, t.TempDir(), [2]string{"xrd.yaml", tc.xrd}), cfg)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nlintPaths(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			var got []string
+			for _, f := range findings {
+				got = append(got, f.Rule+" "+f.Field)
+				if f.Document != tc.wantDoc {
+					t.Errorf("\n%s\nlintPaths(...): got %s finding in document %d, want %d", tc.reason, f.Rule, f.Document, tc.wantDoc)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nlintPaths(...): -want, +got findings:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestLintDefinitions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	defer func(w io.Writer) { messages = w }(messages)
+	messages = buf
+
+	cfg := testConfig()
+	cfg.lintRules = []string{"field-description"}
+	cfg.fix = true
+	paths := writeFiles(t, t.TempDir(), [2]string{"xrd.yaml", baseXRD})
+	if err := lintDefinitions(context.Background(), paths, cfg); err != nil {
+		t.Fatalf("lintDefinitions(...): %v", err)
+	}
+	for _, want := range []string{"Warning: ", "Added 1 TODO descriptions to " + paths[0]} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("lintDefinitions(...): messages lack %q:\n%s", want, buf.String())
+		}
+	}
+}
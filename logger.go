@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// A Logger receives the progress and errors of a conversion. Callers may
+// supply their own to route them elsewhere.
+type Logger interface {
+	// Infof logs the progress of a conversion, such as each file converted.
+	Infof(format string, args ...interface{})
+	// Errorf logs an error, including its wrapped cause.
+	Errorf(format string, args ...interface{})
+}
+
+// NewLogger returns a Logger that writes to w. It only writes progress if
+// verbose is true, but always writes errors.
+func NewLogger(w io.Writer, verbose bool) Logger {
+	return &writerLogger{w: w, verbose: verbose}
+}
+
+type writerLogger struct {
+	w       io.Writer
+	verbose bool
+}
+
+func (l *writerLogger) Infof(format string, args ...interface{}) {
+	if l.verbose {
+		fmt.Fprintf(l.w, format+"\n", args...)
+	}
+}
+
+func (l *writerLogger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, format+"\n", args...)
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
 	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/utils/pointer"
 )
 
@@ -26,11 +40,30 @@ const (
 )
 
 const (
-	errFmtGetProps             = "cannot get %q properties from validation schema"
-	errParseValidation         = "cannot parse validation schema"
-	errInvalidClaimNames       = "invalid resource claim names"
-	errMissingClaimNames       = "missing names"
-	errFmtConflictingClaimName = "%q conflicts with composite resource name"
+	errFmtGetProps              = "cannot get %q properties of version %q from validation schema"
+	errParseValidation          = "cannot parse validation schema"
+	errFmtParseValidationAt     = "cannot parse validation schema at %s"
+	errInvalidClaimNames        = "invalid resource claim names"
+	errMissingClaimNames        = "missing names"
+	errFmtConflictingClaimName  = "%q conflicts with composite resource name"
+	errFmtNotLowercaseClaim     = "claim %s %q must be lowercase"
+	errFmtNotCapitalizedClaim   = "claim kind %q must start with an uppercase letter"
+	errFmtEmptyClaimName        = "claim %s must not be empty"
+	errFmtInvalidClaimName      = "claim %s %q is not a valid DNS label: %s"
+	errTimeout                  = "conversion timed out"
+	errFmtInvalidGroup          = "group %q is not a valid DNS subdomain: %s"
+	errFmtDuplicateCRD          = "CRD %q is generated by both %s and %s"
+	errFmtMultipleReferenceable = "only one version may be referenceable, but %s are"
+	errFmtUnknownStorage        = "storage version %q is not a version of the definition"
+	errFmtUnknownVersion        = "version %q is not a version of the definition"
+	errNoDefinitions            = "no CompositeResourceDefinition found"
+	errFmtNoVersions            = "CompositeResourceDefinition %q defines no versions"
+	errNoDefinitionFiles        = "gen requires at least one definition file"
+	errFmtDefinitionFileIsDir   = "%s is a directory, not a definition file"
+	errGenWatch                 = "--watch cannot be used with gen"
+	errDefaultCompositionRef    = "cannot default composition reference"
+	errFmtNoDefinitions         = "no CompositeResourceDefinition found, only %s"
+	errFmtUnsupportedAPIVersion = "CompositeResourceDefinition %q has unsupported apiVersion %q, want %q or %q"
 )
 
 var PropagateSpecProps = []string{"compositionRef", "compositionSelector", "compositionRevisionRef", "compositionUpdatePolicy"}
@@ -63,6 +96,20 @@ func BaseProps() *extv1.JSONSchemaProps {
 	}
 }
 
+// ConstrainedMetadataProps returns a metadata schema limiting the length of
+// metadata.name and metadata.generateName. The API server rejects CRD schemas
+// that constrain any other metadata field, so labels and annotations are left
+// to it.
+func ConstrainedMetadataProps(maxNameLength int64) extv1.JSONSchemaProps {
+	return extv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]extv1.JSONSchemaProps{
+			"name":         {Type: "string", MaxLength: pointer.Int64(maxNameLength)},
+			"generateName": {Type: "string", MaxLength: pointer.Int64(maxNameLength)},
+		},
+	}
+}
+
 // CompositeResourceSpecProps is a partial OpenAPIV3Schema for the spec fields
 // that Crossplane expects to be present for all defined infrastructure
 // resources.
@@ -304,17 +351,86 @@ func CompositeResourceStatusProps() map[string]extv1.JSONSchemaProps {
 				},
 			},
 		},
-		"connectionDetails": {
-			Type: "object",
-			Properties: map[string]extv1.JSONSchemaProps{
-				"lastPublishedTime": {Type: "string", Format: "date-time"},
-			},
+		"connectionDetails": ConnectionDetailsProps(nil),
+	}
+}
+
+// ConnectionDetailsProps is a partial OpenAPIV3Schema for the connectionDetails
+// status field of a composite resource or claim. Its description documents the
+// supplied keys of the connection secret, if any.
+func ConnectionDetailsProps(keys []string) extv1.JSONSchemaProps {
+	p := extv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]extv1.JSONSchemaProps{
+			"lastPublishedTime": {Type: "string", Format: "date-time"},
 		},
 	}
+	if len(keys) > 0 {
+		p.Description = "Connection details are published to a secret with the keys " + strings.Join(keys, ", ") + "."
+	}
+	return p
+}
+
+// injectedSpecProps returns the supplied Crossplane spec props adjusted
+// according to the supplied XRD and options.
+func injectedSpecProps(p map[string]extv1.JSONSchemaProps, xrd *v1.CompositeResourceDefinition, o *options) (map[string]extv1.JSONSchemaProps, error) {
+	if o.omitCompositionUpdatePolicy {
+		delete(p, "compositionUpdatePolicy")
+	}
+	if ref := xrd.Spec.DefaultCompositionRef; ref != nil && xrd.Spec.EnforcedCompositionRef == nil {
+		d, err := compositionRefDefault(ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		c := p["compositionRef"]
+		c.Default = d
+		p["compositionRef"] = c
+	}
+	if ref := xrd.Spec.EnforcedCompositionRef; ref != nil {
+		// Crossplane replaces any other composition with the enforced one, so
+		// the CRD defaults to it and, where CEL is available, only accepts it.
+		d, err := compositionRefDefault(ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		c := p["compositionRef"]
+		c.Default = d
+		c.Description = fmt.Sprintf("The definition enforces the Composition %q.", ref.Name)
+		if o.celRules {
+			c.XValidations = append(c.XValidations, EnforcedCompositionRefRule(ref.Name))
+		}
+		p["compositionRef"] = c
+	}
+	if d, ok := p["compositeDeletePolicy"]; ok && o.deletePolicy != "" {
+		d.Default = &extv1.JSON{Raw: []byte(strconv.Quote(o.deletePolicy))}
+		p["compositeDeletePolicy"] = d
+	}
+	return p, nil
+}
+
+// compositionRefDefault returns the default of a compositionRef that names
+// the supplied Composition.
+func compositionRefDefault(name string) (*extv1.JSON, error) {
+	d, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return nil, errors.Wrap(err, errDefaultCompositionRef)
+	}
+	return &extv1.JSON{Raw: d}, nil
+}
+
+// injectedStatusProps returns the Crossplane status props to inject alongside
+// the supplied user-defined status props of the supplied XRD.
+func injectedStatusProps(user map[string]extv1.JSONSchemaProps, xrd *v1.CompositeResourceDefinition, o *options) map[string]extv1.JSONSchemaProps {
+	p := CompositeResourceStatusProps()
+	p["connectionDetails"] = ConnectionDetailsProps(xrd.Spec.ConnectionSecretKeys)
+	if o.minimalStatus && len(user) == 0 {
+		delete(p, "connectionDetails")
+	}
+	return p
 }
 
 // CompositeResourcePrinterColumns returns the set of default printer columns
-// that should exist in all generated composite resource CRDs.
+// that should exist in all generated cluster scoped composite resource CRDs.
 func CompositeResourcePrinterColumns() []extv1.CustomResourceColumnDefinition {
 	return []extv1.CustomResourceColumnDefinition{
 		{
@@ -340,6 +456,40 @@ func CompositeResourcePrinterColumns() []extv1.CustomResourceColumnDefinition {
 	}
 }
 
+// NamespacedCompositeResourcePrinterColumns returns the set of default
+// printer columns that should exist in all generated namespaced composite
+// resource CRDs. Like a claim, a namespaced composite resource writes its
+// connection secret to its own namespace, so the secret is shown by name.
+func NamespacedCompositeResourcePrinterColumns() []extv1.CustomResourceColumnDefinition {
+	return []extv1.CustomResourceColumnDefinition{
+		{
+			Name:     "SYNCED",
+			Type:     "string",
+			JSONPath: ".status.conditions[?(@.type=='Synced')].status",
+		},
+		{
+			Name:     "READY",
+			Type:     "string",
+			JSONPath: ".status.conditions[?(@.type=='Ready')].status",
+		},
+		{
+			Name:     "CONNECTION-SECRET",
+			Type:     "string",
+			JSONPath: ".spec.writeConnectionSecretToRef.name",
+		},
+		{
+			Name:     "COMPOSITION",
+			Type:     "string",
+			JSONPath: ".spec.compositionRef.name",
+		},
+		{
+			Name:     "AGE",
+			Type:     "date",
+			JSONPath: ".metadata.creationTimestamp",
+		},
+	}
+}
+
 // CompositeResourceClaimPrinterColumns returns the set of default printer
 // columns that should exist in all generated composite resource claim CRDs.
 func CompositeResourceClaimPrinterColumns() []extv1.CustomResourceColumnDefinition {
@@ -367,40 +517,101 @@ func CompositeResourceClaimPrinterColumns() []extv1.CustomResourceColumnDefiniti
 	}
 }
 
-// GetPropFields returns the fields from a map of schema properties
-func GetPropFields(props map[string]extv1.JSONSchemaProps) []string {
-	propFields := make([]string, len(props))
-	i := 0
-	for k := range props {
-		propFields[i] = k
-		i++
+// shortNamesFor returns the short names of a CRD derived from an XRD with the
+// supplied short names: the XRD's own, followed by those supplied as options.
+// It returns nil if there are none, as the XRD would.
+func shortNamesFor(own []string, o *options) []string {
+	if len(own) == 0 && len(o.shortNames) == 0 {
+		return nil
+	}
+	return dedupe(append(append([]string{}, own...), o.shortNames...))
+}
+
+// versionPrinterColumns returns the printer columns of a version: those the
+// version defines, followed by those shared by all versions and the defaults.
+// Earlier columns take precedence over later ones of the same name.
+func versionPrinterColumns(version, shared, defaults []extv1.CustomResourceColumnDefinition) []extv1.CustomResourceColumnDefinition {
+	return mergePrinterColumns(mergePrinterColumns(version, shared), defaults)
+}
+
+// mergePrinterColumns returns the supplied user columns followed by those
+// defaults whose name no user column has. kubectl shows column names in upper
+// case, so names are compared case insensitively.
+func mergePrinterColumns(user, defaults []extv1.CustomResourceColumnDefinition) []extv1.CustomResourceColumnDefinition {
+	cols := make([]extv1.CustomResourceColumnDefinition, 0, len(user)+len(defaults))
+	seen := map[string]bool{}
+	for _, c := range append(append([]extv1.CustomResourceColumnDefinition{}, user...), defaults...) {
+		name := strings.ToUpper(c.Name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		cols = append(cols, c)
 	}
-	return propFields
+	return cols
+}
+
+// GetPropFields returns the sorted fields from a map of schema properties, so
+// that anything derived from them is the same on every run.
+func GetPropFields(props map[string]extv1.JSONSchemaProps) []string {
+	return sortedKeys(props)
 }
 
-func ForCompositeResource(xrd *v1.CompositeResourceDefinition) (*extv1.CustomResourceDefinition, error) {
+func ForCompositeResource(xrd *v1.CompositeResourceDefinition, opts ...Option) (*extv1.CustomResourceDefinition, error) {
+	o := newOptions(opts)
+
+	if len(xrd.Spec.Versions) == 0 {
+		return nil, errors.Errorf(errFmtNoVersions, xrd.GetName())
+	}
+
+	group, err := groupFor(xrd, o)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := scaleFor(xrd, o)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := scopeFor(o)
+	if err != nil {
+		return nil, err
+	}
+
 	crd := &extv1.CustomResourceDefinition{
 		Spec: extv1.CustomResourceDefinitionSpec{
-			Scope:    extv1.ClusterScoped,
-			Group:    xrd.Spec.Group,
-			Names:    xrd.Spec.Names,
-			Versions: make([]extv1.CustomResourceDefinitionVersion, len(xrd.Spec.Versions)),
+			Scope:      scope,
+			Group:      group,
+			Names:      xrd.Spec.Names,
+			Versions:   make([]extv1.CustomResourceDefinitionVersion, len(xrd.Spec.Versions)),
+			Conversion: conversionFor(o),
 		},
 	}
 
 	crd.SetName(xrd.GetName())
-	crd.SetLabels(xrd.GetLabels())
+	if o.groupSuffix != "" {
+		crd.SetName(xrd.Spec.Names.Plural + "." + group)
+	}
+	crd.SetLabels(mergeStrings(xrd.GetLabels(), o.labels))
+	crd.SetAnnotations(mergeStrings(definitionAnnotations(xrd), o.annotations))
+
+	crd.Spec.Names.Categories = categoriesFor(xrd, crd.Spec.Names.Categories, AnnotationCompositeCategories, CategoryComposite, o)
+	crd.Spec.Names.ShortNames = shortNamesFor(crd.Spec.Names.ShortNames, o)
 
-	crd.Spec.Names.Categories = append(crd.Spec.Names.Categories, CategoryComposite)
+	columns := CompositeResourcePrinterColumns()
+	if scope == extv1.NamespaceScoped {
+		columns = NamespacedCompositeResourcePrinterColumns()
+	}
 
 	for i, vr := range xrd.Spec.Versions {
 		crd.Spec.Versions[i] = extv1.CustomResourceDefinitionVersion{
 			Name:                     vr.Name,
-			Served:                   vr.Served,
+			Served:                   vr.Served || o.serveAll,
 			Storage:                  vr.Referenceable,
 			Deprecated:               pointer.BoolDeref(vr.Deprecated, false),
 			DeprecationWarning:       vr.DeprecationWarning,
-			AdditionalPrinterColumns: append(vr.AdditionalPrinterColumns, CompositeResourcePrinterColumns()...),
+			AdditionalPrinterColumns: versionPrinterColumns(vr.AdditionalPrinterColumns, o.printerColumns, columns),
 			Schema: &extv1.CustomResourceValidation{
 				OpenAPIV3Schema: BaseProps(),
 			},
@@ -409,33 +620,72 @@ func ForCompositeResource(xrd *v1.CompositeResourceDefinition) (*extv1.CustomRes
 			},
 		}
 
-		p, required, err := getProps("spec", vr.Schema)
+		if o.maxNameLength > 0 {
+			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["metadata"] = ConstrainedMetadataProps(o.maxNameLength)
+		}
+		if root := userSchema(vr); root != nil {
+			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.XValidations = root.XValidations
+		}
+
+		userSpec, err := getSchema("spec", vr.Schema)
 		if err != nil {
-			return nil, errors.Wrapf(err, errFmtGetProps, "spec")
+			return nil, errors.Wrapf(err, errFmtGetProps, "spec", vr.Name)
 		}
-		specProps := crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"]
+		p, required := userSpec.Properties, userSpec.Required
+		remapEnums(p, o.enumMapping)
+		p, required = withBaseProps(o.baseSchema, "spec", p, required)
+		if o.requireUserFields {
+			required = dedupe(append(required, sortedKeys(p)...))
+		}
+		specProps := withUserFacets(crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"], userSpec)
 		specProps.Required = append(specProps.Required, required...)
 		for k, v := range p {
 			specProps.Properties[k] = v
 		}
-		for k, v := range CompositeResourceSpecProps() {
+		injected, err := injectedSpecProps(CompositeResourceSpecProps(), xrd, o)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range injected {
 			specProps.Properties[k] = v
 		}
+		if o.exclusiveRefs {
+			specProps.XValidations = append(specProps.XValidations, ExclusiveCompositionRefsRule())
+		}
 		crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"] = specProps
 
-		statusP, statusRequired, err := getProps("status", vr.Schema)
+		userStatus, err := getSchema("status", vr.Schema)
 		if err != nil {
-			return nil, errors.Wrapf(err, errFmtGetProps, "status")
+			return nil, errors.Wrapf(err, errFmtGetProps, "status", vr.Name)
 		}
-		statusProps := crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"]
-		statusProps.Required = statusRequired
+		statusP, statusRequired := userStatus.Properties, userStatus.Required
+		remapEnums(statusP, o.enumMapping)
+		statusP, statusRequired = withBaseProps(o.baseSchema, "status", statusP, statusRequired)
+		statusProps := withUserFacets(crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"], userStatus)
+		statusProps.Required = append(statusProps.Required, statusRequired...)
 		for k, v := range statusP {
 			statusProps.Properties[k] = v
 		}
-		for k, v := range CompositeResourceStatusProps() {
+		for k, v := range injectedStatusProps(statusP, xrd, o) {
 			statusProps.Properties[k] = v
 		}
 		crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"] = statusProps
+
+		if err := setScale(&crd.Spec.Versions[i], scale); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := filterVersions(crd, o.versions); err != nil {
+		return nil, err
+	}
+
+	if err := setStorageVersion(crd, o.storageVersion); err != nil {
+		return nil, err
+	}
+
+	for i := range crd.Spec.Versions {
+		sortRequired(crd.Spec.Versions[i].Schema.OpenAPIV3Schema)
 	}
 
 	return crd, nil
@@ -443,33 +693,64 @@ func ForCompositeResource(xrd *v1.CompositeResourceDefinition) (*extv1.CustomRes
 
 // ForCompositeResourceClaim derives the CustomResourceDefinition for a
 // composite resource claim from the supplied CompositeResourceDefinition.
-func ForCompositeResourceClaim(xrd *v1.CompositeResourceDefinition) (*extv1.CustomResourceDefinition, error) {
+func ForCompositeResourceClaim(xrd *v1.CompositeResourceDefinition, opts ...Option) (*extv1.CustomResourceDefinition, error) {
+	o := newOptions(opts)
+
+	if len(xrd.Spec.Versions) == 0 {
+		return nil, errors.Errorf(errFmtNoVersions, xrd.GetName())
+	}
+
+	if xrd.Spec.ClaimNames == nil && o.claimNamesConvention != "" {
+		n, err := DeriveClaimNames(xrd.Spec.Names, o.claimNamesConvention)
+		if err != nil {
+			return nil, errors.Wrap(err, errInvalidClaimNames)
+		}
+		xrd = xrd.DeepCopy()
+		xrd.Spec.ClaimNames = &n
+	}
+
 	if err := validateClaimNames(xrd); err != nil {
 		return nil, errors.Wrap(err, errInvalidClaimNames)
 	}
 
+	group, err := groupFor(xrd, o)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := scaleFor(xrd, o)
+	if err != nil {
+		return nil, err
+	}
+
 	crd := &extv1.CustomResourceDefinition{
 		Spec: extv1.CustomResourceDefinitionSpec{
-			Scope:    extv1.NamespaceScoped,
-			Group:    xrd.Spec.Group,
-			Names:    *xrd.Spec.ClaimNames,
-			Versions: make([]extv1.CustomResourceDefinitionVersion, len(xrd.Spec.Versions)),
+			Scope:      extv1.NamespaceScoped,
+			Group:      group,
+			Names:      *xrd.Spec.ClaimNames.DeepCopy(),
+			Versions:   make([]extv1.CustomResourceDefinitionVersion, len(xrd.Spec.Versions)),
+			Conversion: conversionFor(o),
 		},
 	}
 
-	crd.SetName(xrd.Spec.ClaimNames.Plural + "." + xrd.Spec.Group)
-	crd.SetLabels(xrd.GetLabels())
+	crd.SetName(xrd.Spec.ClaimNames.Plural + "." + group)
+	crd.SetLabels(mergeStrings(xrd.GetLabels(), o.labels))
+	crd.SetAnnotations(mergeStrings(definitionAnnotations(xrd), o.annotations))
 
-	crd.Spec.Names.Categories = append(crd.Spec.Names.Categories, CategoryClaim)
+	crd.Spec.Names.Categories = categoriesFor(xrd, crd.Spec.Names.Categories, AnnotationClaimCategories, CategoryClaim, o)
+	crd.Spec.Names.ShortNames = shortNamesFor(crd.Spec.Names.ShortNames, o)
+	if err := validateClaimShortNames(xrd.Spec.Names.ShortNames, crd.Spec.Names.ShortNames); err != nil {
+		return nil, errors.Wrap(err, errInvalidClaimNames)
+	}
 
 	for i, vr := range xrd.Spec.Versions {
 		crd.Spec.Versions[i] = extv1.CustomResourceDefinitionVersion{
 			Name:                     vr.Name,
-			Served:                   vr.Served,
+			Served:                   vr.Served || o.serveAll,
 			Storage:                  vr.Referenceable,
 			Deprecated:               pointer.BoolDeref(vr.Deprecated, false),
 			DeprecationWarning:       vr.DeprecationWarning,
-			AdditionalPrinterColumns: append(vr.AdditionalPrinterColumns, CompositeResourceClaimPrinterColumns()...),
+			AdditionalPrinterColumns: versionPrinterColumns(vr.AdditionalPrinterColumns, o.printerColumns, CompositeResourceClaimPrinterColumns()),
 			Schema: &extv1.CustomResourceValidation{
 				OpenAPIV3Schema: BaseProps(),
 			},
@@ -478,169 +759,1480 @@ func ForCompositeResourceClaim(xrd *v1.CompositeResourceDefinition) (*extv1.Cust
 			},
 		}
 
-		p, required, err := getProps("spec", vr.Schema)
+		if o.maxNameLength > 0 {
+			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["metadata"] = ConstrainedMetadataProps(o.maxNameLength)
+		}
+		if root := userSchema(vr); root != nil {
+			crd.Spec.Versions[i].Schema.OpenAPIV3Schema.XValidations = root.XValidations
+		}
+
+		userSpec, err := getSchema("spec", vr.Schema)
 		if err != nil {
-			return nil, errors.Wrapf(err, errFmtGetProps, "spec")
+			return nil, errors.Wrapf(err, errFmtGetProps, "spec", vr.Name)
 		}
-		specProps := crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"]
+		p, required := userSpec.Properties, userSpec.Required
+		remapEnums(p, o.enumMapping)
+		p, required = withBaseProps(o.baseSchema, "spec", p, required)
+		if o.requireUserFields {
+			required = dedupe(append(required, sortedKeys(p)...))
+		}
+		specProps := withUserFacets(crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"], userSpec)
 		specProps.Required = append(specProps.Required, required...)
 		for k, v := range p {
 			specProps.Properties[k] = v
 		}
-		for k, v := range CompositeResourceClaimSpecProps() {
+		injected, err := injectedSpecProps(CompositeResourceClaimSpecProps(), xrd, o)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range injected {
 			specProps.Properties[k] = v
 		}
+		if o.exclusiveRefs {
+			specProps.XValidations = append(specProps.XValidations, ExclusiveCompositionRefsRule())
+		}
 		crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["spec"] = specProps
 
-		statusP, statusRequired, err := getProps("status", vr.Schema)
+		userStatus, err := getSchema("status", vr.Schema)
 		if err != nil {
-			return nil, errors.Wrapf(err, errFmtGetProps, "status")
+			return nil, errors.Wrapf(err, errFmtGetProps, "status", vr.Name)
 		}
-		statusProps := crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"]
-		statusProps.Required = statusRequired
+		statusP, statusRequired := userStatus.Properties, userStatus.Required
+		remapEnums(statusP, o.enumMapping)
+		statusP, statusRequired = withBaseProps(o.baseSchema, "status", statusP, statusRequired)
+		statusProps := withUserFacets(crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"], userStatus)
+		statusProps.Required = append(statusProps.Required, statusRequired...)
 		for k, v := range statusP {
 			statusProps.Properties[k] = v
 		}
-		for k, v := range CompositeResourceStatusProps() {
+		for k, v := range injectedStatusProps(statusP, xrd, o) {
 			statusProps.Properties[k] = v
 		}
 		crd.Spec.Versions[i].Schema.OpenAPIV3Schema.Properties["status"] = statusProps
+
+		if err := setScale(&crd.Spec.Versions[i], scale); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := filterVersions(crd, o.versions); err != nil {
+		return nil, err
+	}
+
+	if err := setStorageVersion(crd, o.storageVersion); err != nil {
+		return nil, err
+	}
+
+	for i := range crd.Spec.Versions {
+		sortRequired(crd.Spec.Versions[i].Schema.OpenAPIV3Schema)
 	}
 
 	return crd, nil
 }
 
+// groupFor returns the API group of the CRDs derived from the supplied XRD.
+func groupFor(xrd *v1.CompositeResourceDefinition, o *options) (string, error) {
+	group := xrd.Spec.Group + o.groupSuffix
+	if o.groupSuffix == "" {
+		return group, nil
+	}
+	if errs := validation.IsDNS1123Subdomain(group); len(errs) > 0 {
+		return "", errors.Errorf(errFmtInvalidGroup, group, strings.Join(errs, ", "))
+	}
+	return group, nil
+}
+
+// filterVersions keeps only the named versions of the supplied CRD, in their
+// original order. It keeps every version if no names are supplied.
+func filterVersions(crd *extv1.CustomResourceDefinition, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	keep := map[string]bool{}
+	for _, n := range names {
+		keep[n] = true
+	}
+	versions := make([]extv1.CustomResourceDefinitionVersion, 0, len(names))
+	for _, v := range crd.Spec.Versions {
+		if keep[v.Name] {
+			versions = append(versions, v)
+			delete(keep, v.Name)
+		}
+	}
+	for _, n := range names {
+		if keep[n] {
+			return errors.Errorf(errFmtUnknownVersion, n)
+		}
+	}
+	crd.Spec.Versions = versions
+	return nil
+}
+
+// setStorageVersion marks the named version, and only that version, of the
+// supplied CRD as its storage version. If name is empty the version derived
+// from the referenceable version of the XRD is kept, or the latest served
+// version is stored if no version was referenceable.
+func setStorageVersion(crd *extv1.CustomResourceDefinition, name string) error {
+	if name == "" {
+		var stored []string
+		for _, v := range crd.Spec.Versions {
+			if v.Storage {
+				stored = append(stored, v.Name)
+			}
+		}
+		switch {
+		case len(stored) > 1:
+			return errors.Errorf(errFmtMultipleReferenceable, strings.Join(stored, ", "))
+		case len(stored) == 1 || len(crd.Spec.Versions) == 0:
+			return nil
+		}
+		name = latestServedVersion(crd.Spec.Versions)
+	}
+	found := false
+	for i := range crd.Spec.Versions {
+		v := &crd.Spec.Versions[i]
+		v.Storage = v.Name == name
+		found = found || v.Storage
+	}
+	if !found {
+		return errors.Errorf(errFmtUnknownStorage, name)
+	}
+	return nil
+}
+
+// latestServedVersion returns the name of the latest served of the supplied
+// versions, by Kubernetes version priority, or of the latest version if none
+// is served.
+func latestServedVersion(versions []extv1.CustomResourceDefinitionVersion) string {
+	latest, served := "", false
+	for _, v := range versions {
+		switch {
+		case latest == "",
+			v.Served && !served,
+			v.Served == served && version.CompareKubeAwareVersionStrings(v.Name, latest) > 0:
+			latest, served = v.Name, v.Served
+		}
+	}
+	return latest
+}
+
 func validateClaimNames(d *v1.CompositeResourceDefinition) error {
 	if d.Spec.ClaimNames == nil {
 		return errors.New(errMissingClaimNames)
 	}
 
-	if n := d.Spec.ClaimNames.Kind; n == d.Spec.Names.Kind {
+	return validateClaimNamesFor(d.Spec.Names, *d.Spec.ClaimNames)
+}
+
+func validateClaimNamesFor(composite, claim extv1.CustomResourceDefinitionNames) error {
+	if err := validateClaimNameFormat(&claim); err != nil {
+		return err
+	}
+
+	if n := claim.Kind; n == composite.Kind {
 		return errors.Errorf(errFmtConflictingClaimName, n)
 	}
 
-	if n := d.Spec.ClaimNames.Plural; n == d.Spec.Names.Plural {
+	if n := claim.Plural; n == composite.Plural {
 		return errors.Errorf(errFmtConflictingClaimName, n)
 	}
 
-	if n := d.Spec.ClaimNames.Singular; n != "" && n == d.Spec.Names.Singular {
+	if n := claim.Singular; n != "" && n == composite.Singular {
 		return errors.Errorf(errFmtConflictingClaimName, n)
 	}
 
-	if n := d.Spec.ClaimNames.ListKind; n != "" && n == d.Spec.Names.ListKind {
+	if n := listKind(claim); n == listKind(composite) {
 		return errors.Errorf(errFmtConflictingClaimName, n)
 	}
 
+	return validateClaimShortNames(composite.ShortNames, claim.ShortNames)
+}
+
+// validateClaimShortNames checks that none of the supplied claim short names
+// is also a composite resource short name.
+func validateClaimShortNames(composite, claim []string) error {
+	for _, n := range claim {
+		if contains(composite, n) {
+			return errors.Errorf(errFmtConflictingClaimName, n)
+		}
+	}
 	return nil
 }
 
-func getProps(field string, v *v1.CompositeResourceValidation) (map[string]extv1.JSONSchemaProps, []string, error) {
-	if v == nil {
-		return nil, nil, nil
+// listKind returns the list kind of the supplied names, defaulted as the API
+// server would.
+func listKind(n extv1.CustomResourceDefinitionNames) string {
+	if n.ListKind != "" {
+		return n.ListKind
+	}
+	return n.Kind + "List"
+}
+
+// validateClaimNameFormat checks that the supplied claim names are ones the
+// API server would accept. The plural names the CRD, and the kind names its
+// resources, so neither may be empty.
+func validateClaimNameFormat(n *extv1.CustomResourceDefinitionNames) error {
+	if n.Plural == "" {
+		return errors.Errorf(errFmtEmptyClaimName, "plural")
+	}
+
+	if n.Kind == "" {
+		return errors.Errorf(errFmtEmptyClaimName, "kind")
+	}
+
+	if n.Plural != strings.ToLower(n.Plural) {
+		return errors.Errorf(errFmtNotLowercaseClaim, "plural", n.Plural)
+	}
+
+	if n.Singular != strings.ToLower(n.Singular) {
+		return errors.Errorf(errFmtNotLowercaseClaim, "singular", n.Singular)
+	}
+
+	for _, sn := range n.ShortNames {
+		if sn != strings.ToLower(sn) {
+			return errors.Errorf(errFmtNotLowercaseClaim, "short name", sn)
+		}
+	}
+
+	if r, _ := utf8.DecodeRuneInString(n.Kind); !unicode.IsUpper(r) {
+		return errors.Errorf(errFmtNotCapitalizedClaim, n.Kind)
+	}
+
+	// The API server requires these to be RFC 1035 labels, the kind once
+	// lowercased.
+	if errs := validation.IsDNS1035Label(strings.ToLower(n.Kind)); len(errs) > 0 {
+		return errors.Errorf(errFmtInvalidClaimName, "kind", n.Kind, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1035Label(n.Plural); len(errs) > 0 {
+		return errors.Errorf(errFmtInvalidClaimName, "plural", n.Plural, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1035Label(n.Singular); n.Singular != "" && len(errs) > 0 {
+		return errors.Errorf(errFmtInvalidClaimName, "singular", n.Singular, strings.Join(errs, ", "))
+	}
+	for _, sn := range n.ShortNames {
+		if errs := validation.IsDNS1035Label(sn); len(errs) > 0 {
+			return errors.Errorf(errFmtInvalidClaimName, "short name", sn, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
+// getSchema returns the schema of the named top-level field of the supplied
+// validation schema, such as its properties, required fields and description.
+// Properties are returned whole, so extensions such as
+// x-kubernetes-embedded-resource are preserved on them. A version without a
+// schema still gets the base and Crossplane props, so it returns an empty
+// schema.
+func getSchema(field string, v *v1.CompositeResourceValidation) (extv1.JSONSchemaProps, error) {
+	if v == nil || len(v.OpenAPIV3Schema.Raw) == 0 {
+		return extv1.JSONSchemaProps{}, nil
 	}
 
 	s := &extv1.JSONSchemaProps{}
 	if err := json.Unmarshal(v.OpenAPIV3Schema.Raw, s); err != nil {
-		return nil, nil, errors.Wrap(err, errParseValidation)
+		return extv1.JSONSchemaProps{}, wrapParseError(err)
 	}
 
-	spec, ok := s.Properties[field]
-	if !ok {
-		return nil, nil, nil
+	spec := s.Properties[field]
+	if err := checkDefaults(field, spec.Properties); err != nil {
+		return extv1.JSONSchemaProps{}, err
 	}
 
-	return spec.Properties, spec.Required, nil
+	return spec, nil
 }
 
-func loadXrd(path string) (*v1.CompositeResourceDefinition, error) {
-	y, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
+// wrapParseError wraps an error returned when unmarshalling a validation
+// schema, naming the JSON path of the offending field or the byte offset of
+// the syntax error where the error reveals one.
+func wrapParseError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return errors.Wrapf(err, errFmtParseValidationAt, "openAPIV3Schema."+typeErr.Field)
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return errors.Wrapf(err, errFmtParseValidationAt, fmt.Sprintf("byte %d", syntaxErr.Offset))
+	}
+	return errors.Wrap(err, errParseValidation)
+}
+
+// A definition is an XRD along with what the v1 API types can't hold of
+// newer XRD API versions.
+type definition struct {
+	*v1.CompositeResourceDefinition
+
+	// scope of the composite resources a v2 XRD defines. It is empty for v1
+	// XRDs, whose composite resources are cluster scoped.
+	scope string
+
+	// doc is the YAML document the definition was decoded from, and path
+	// and index the file and position in it of that document.
+	doc   []byte
+	path  string
+	index int
+}
+
+// source describes where the definition was read from, telling apart the
+// definitions of a multi-document file.
+func (d *definition) source() string {
+	if d.index == 0 {
+		return d.path
+	}
+	return fmt.Sprintf("%s (document %d)", d.path, d.index+1)
+}
+
+// compositeOptions returns the supplied options followed by those the
+// definition itself calls for when deriving its composite resource CRD.
+func (d *definition) compositeOptions(opts []Option) []Option {
+	if d.scope == "" {
+		return opts
 	}
-	var xrd v1.CompositeResourceDefinition
-	err = yaml.Unmarshal(y, &xrd)
+	return append(append([]Option{}, opts...), WithScope(d.scope))
+}
+
+// loadXrd loads the first definition in the file at the supplied path.
+func loadXrd(path string) (*definition, error) {
+	xrds, err := loadXrds(path)
 	if err != nil {
 		return nil, err
 	}
-	return &xrd, nil
+	return xrds[0], nil
 }
 
-func generateCrdForPaths(paths []string, oututFolder string) error {
-	err := generateCrdForPathsOfType(paths, oututFolder, ForCompositeResource)
+// loadXrds loads every definition in the file at the supplied path.
+func loadXrds(path string) ([]*definition, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = generateCrdForPathsOfType(paths, oututFolder, ForCompositeResourceClaim)
-	if err != nil {
-		return err
+	defer f.Close()
+	xrds, err := readXrds(f)
+	for _, xrd := range xrds {
+		xrd.path = path
 	}
-	return nil
+	return xrds, err
 }
 
-func generateCrdForPathsOfType(paths []string, oututFolder string, generator func(xrd *v1.CompositeResourceDefinition) (*extv1.CustomResourceDefinition, error)) error {
-	for _, m := range paths {
-		fmt.Println(m)
-
-		xrd, _ := loadXrd(m)
-
-		crd, err := generator(xrd)
-		crd.Kind = "CustomResourceDefinition"
-		crd.APIVersion = "apiextensions.k8s.io/v1"
+// readXrds reads every definition from the supplied YAML stream, skipping
+// documents of any other kind. It returns an error if there is no definition,
+// naming the kinds it found instead.
+func readXrds(r io.Reader) ([]*definition, error) {
+	docs, err := readDocuments(r)
+	if err != nil {
+		return nil, err
+	}
+	var xrds []*definition
+	var others []string
+	for i, doc := range docs {
+		t, err := typeOf(doc)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if t.Kind != v1.CompositeResourceDefinitionKind {
+			others = append(others, describeKind(t))
+			continue
 		}
-		y, err := yaml.Marshal(crd)
+		xrd, err := decodeXrd(doc)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		xrd.index = i
+		xrds = append(xrds, xrd)
+	}
+	if len(xrds) == 0 {
+		return nil, noDefinitionsError(others)
+	}
+	return xrds, nil
+}
 
-		output := filepath.Join(oututFolder, "/crds/", fmt.Sprintf("%s_%s.yaml", crd.Spec.Group, crd.Spec.Names.Plural))
+// typeOf returns the type of the supplied YAML document.
+func typeOf(doc []byte) (metav1.TypeMeta, error) {
+	t := metav1.TypeMeta{}
+	err := yaml.Unmarshal(doc, &t)
+	return t, err
+}
 
-		err = ioutil.WriteFile(output, y, 0644)
+// decodeXrd decodes the supplied YAML document, which must be a definition of
+// a supported API version.
+func decodeXrd(doc []byte) (*definition, error) {
+	xrd := &definition{CompositeResourceDefinition: &v1.CompositeResourceDefinition{}, doc: doc}
+	if err := yaml.Unmarshal(doc, xrd.CompositeResourceDefinition); err != nil {
+		return nil, err
+	}
+	switch xrd.APIVersion {
+	case v1.SchemeGroupVersion.String():
+		clearBookkeeping(xrd.CompositeResourceDefinition)
+	case APIVersionV2:
+		clearBookkeeping(xrd.CompositeResourceDefinition)
+		scope, err := readScope(doc)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		xrd.scope = scope
+	default:
+		return nil, errors.Errorf(errFmtUnsupportedAPIVersion, xrd.GetName(), xrd.APIVersion, v1.SchemeGroupVersion, APIVersionV2)
 	}
-	return nil
-}
-
-func findPathsForPattern(pattern string, cwd string) ([]string, error) {
-	iGlob := filepath.Join(cwd, "*/", pattern)
-	ml, err := filepath.Glob(iGlob)
-	if err != nil {
+	if err := readSpecMetadata(doc, xrd.CompositeResourceDefinition); err != nil {
 		return nil, err
 	}
+	if len(xrd.Spec.Versions) == 0 {
+		return nil, errors.Errorf(errFmtNoVersions, xrd.GetName())
+	}
+	return xrd, nil
+}
 
-	return ml, nil
+// noDefinitionsError returns the error for a file without definitions, naming
+// the kinds of the documents it holds instead.
+func noDefinitionsError(others []string) error {
+	if len(others) == 0 {
+		return errors.New(errNoDefinitions)
+	}
+	return errors.Errorf(errFmtNoDefinitions, strings.Join(others, ", "))
 }
 
-func generateCrdsForPattern(pattern string, cwd string) error {
-	ml, err := findPathsForPattern(pattern, cwd)
-	if err != nil {
-		return err
+// describeKind describes the kind of a document for error messages.
+func describeKind(t metav1.TypeMeta) string {
+	switch {
+	case t.Kind == "":
+		return "a document without a kind"
+	case t.APIVersion == "":
+		return fmt.Sprintf("kind %q", t.Kind)
 	}
+	return fmt.Sprintf("kind %q of %s", t.Kind, t.APIVersion)
+}
 
-	err = generateCrdForPaths(ml, cwd)
+// annotationPrefix prefixes the annotations that configure this tool. They
+// are not copied to generated CRDs.
+const annotationPrefix = "xrdconvert.dev/"
 
-	return err
+// definitionAnnotations returns the annotations of the supplied XRD to copy to
+// the CRDs derived from it.
+func definitionAnnotations(xrd *v1.CompositeResourceDefinition) map[string]string {
+	a := map[string]string{}
+	for k, v := range xrd.GetAnnotations() {
+		if !strings.HasPrefix(k, annotationPrefix) {
+			a[k] = v
+		}
+	}
+	return a
 }
 
-func main() {
-	cwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(err)
+// clearBookkeeping drops the metadata and status that the API server
+// maintains, so that definitions exported from a cluster convert exactly like
+// their source manifests.
+func clearBookkeeping(xrd *v1.CompositeResourceDefinition) {
+	xrd.ObjectMeta = metav1.ObjectMeta{
+		Name:        xrd.GetName(),
+		Labels:      xrd.GetLabels(),
+		Annotations: xrd.GetAnnotations(),
 	}
-	definitionFile := "xrd.yaml"
-	err = generateCrdsForPattern(definitionFile, cwd)
+	delete(xrd.Annotations, corev1.LastAppliedConfigAnnotation)
+	xrd.Status = v1.CompositeResourceDefinitionStatus{}
+}
 
+// A generatorFunc derives a CRD from a definition. It returns a nil CRD and
+// no error if the definition does not call for the CRD.
+type generatorFunc func(xrd *definition) (*extv1.CustomResourceDefinition, error)
+
+func generateCrdForPaths(ctx context.Context, paths []string, outputFolder string, cfg *config) error {
+	cfg.generated = map[string]string{}
+	cfg.outputs = nil
+	cfg.failures = &batchError{}
+
+	if cfg.dumpIntermediate && cfg.dryRun == "" {
+		if err := dumpIntermediate(paths, filepath.Dir(outputFolder), cfg); err != nil {
+			return err
+		}
+	}
+
+	generators := generatorsFor(cfg.compositeOptions(), cfg.claimOptions(), cfg.log)
+
+	if cfg.singleFile {
+		for _, m := range paths {
+			if err := checkContext(ctx); err != nil {
+				return err
+			}
+			if err := generateCrdsForPath(ctx, m, outputFolder, cfg, generators); err != nil {
+				return err
+			}
+		}
+		return cfg.failures.errOrNil()
+	}
+
+	// Each path is loaded once for all generators, so that a path that fails
+	// to load is only reported once.
+	loaded, err := loadPaths(ctx, paths, cfg)
+	if err != nil {
+		return err
+	}
+	for _, generator := range generators {
+		if err := generateCrdForPathsOfType(ctx, loaded, outputFolder, cfg, generator); err != nil {
+			return err
+		}
+	}
+	return cfg.failures.errOrNil()
+}
+
+// A loadedPath is a path along with the definitions loaded from it.
+type loadedPath struct {
+	path string
+	xrds []*definition
+}
+
+// loadPaths loads the definitions at each of the supplied paths. A path that
+// fails to load is reported and left out of the returned paths.
+func loadPaths(ctx context.Context, paths []string, cfg *config) ([]loadedPath, error) {
+	loaded := make([]loadedPath, 0, len(paths))
+	for _, m := range paths {
+		if err := checkContext(ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		xrds, err := cfg.load(m)
+		if err != nil {
+			fr := &fileReport{Input: m}
+			err = errors.Wrapf(err, errFmtLoadXrd, m)
+			cfg.report.record(fr, time.Since(start), err)
+			cfg.sarif.addFileReport(fr, err)
+			if err := cfg.fail(m, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		loaded = append(loaded, loadedPath{path: m, xrds: xrds})
+	}
+	return loaded, nil
+}
+
+func generateCrdForPathsOfType(ctx context.Context, loaded []loadedPath, outputFolder string, cfg *config, generator generatorFunc) error {
+	for _, l := range loaded {
+		for _, xrd := range l.xrds {
+			if err := checkContext(ctx); err != nil {
+				return err
+			}
+			start := time.Now()
+			fr := &fileReport{Input: l.path}
+			err := generateCrdForPath(ctx, l.path, xrd, outputFolder, cfg, generator, fr)
+			cfg.report.record(fr, time.Since(start), err)
+			cfg.sarif.addFileReport(fr, err)
+			if err := cfg.fail(l.path, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func generateCrdForPath(ctx context.Context, m string, xrd *definition, outputFolder string, cfg *config, generator generatorFunc, fr *fileReport) error {
+	// The output file is named after the generated CRD, so the CRD is
+	// generated, but not rendered, to tell whether its output is up to date.
+	if cfg.outputMode == OutputFiles {
+		crd, err := generator(xrd)
+		if err != nil {
+			return err
+		}
+		if crd == nil {
+			fr.Status = FileStatusSkipped
+			return nil
+		}
+		output, err := outputPath(outputFolder, m, crd, cfg)
+		if err != nil {
+			return err
+		}
+		if upToDateOutput(output, m, cfg) {
+			fr.CRD = crd.GetName()
+			fr.Status = FileStatusUpToDate
+			return keepUpToDate(output, xrd, []*extv1.CustomResourceDefinition{crd}, outputFolder, cfg)
+		}
+		generator = func(*definition) (*extv1.CustomResourceDefinition, error) { return crd, nil }
+	}
+
+	render := func(xrd *definition, generator generatorFunc) (*extv1.CustomResourceDefinition, []byte, error) {
+		return renderCrd(ctx, m, xrd, cfg, generator, fr)
+	}
+	sink := func(crd *extv1.CustomResourceDefinition, y []byte) error {
+		output, err := outputPath(outputFolder, m, crd, cfg)
+		if err != nil {
+			return err
+		}
+		return writeOutput(ctx, m, output, y, cfg, fr)
+	}
+	if cfg.outputMode == OutputStdout {
+		fr.Output = "-"
+		sink = streamTo(os.Stdout, cfg.format)
+	}
+
+	crds, err := emitCRDs(xrd, []generatorFunc{generator}, render, sink)
+	if err != nil {
+		return err
+	}
+	if len(crds) == 0 {
+		fr.Status = FileStatusSkipped
+		return nil
+	}
+
+	// The webhook stub is compared to its own output, so that it is written
+	// even if the CRD is up to date.
+	if cfg.webhook != nil && cfg.outputMode != OutputStdout && cfg.dryRun == "" {
+		return writeWebhook(crds[0], filepath.Dir(outputFolder), cfg.webhook)
+	}
+	return nil
+}
+
+// generateCrdsForPath generates the CRDs derived from each definition at the
+// supplied path by each of the supplied generators into a single file per
+// definition, named after the definition.
+func generateCrdsForPath(ctx context.Context, m string, outputFolder string, cfg *config, generators []generatorFunc) error {
+	start := time.Now()
+	xrds, err := cfg.load(m)
+	if err != nil {
+		fr := &fileReport{Input: m}
+		err = errors.Wrapf(err, errFmtLoadXrd, m)
+		cfg.report.record(fr, time.Since(start), err)
+		cfg.sarif.addFileReport(fr, err)
+		return cfg.fail(m, err)
+	}
+	for _, xrd := range xrds {
+		err := generateCrdsForDefinition(ctx, m, xrd, outputFolder, cfg, generators)
+		if err := cfg.fail(m, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateCrdsForDefinition generates the CRDs derived from the supplied
+// definition, read from the supplied path, into a single file.
+func generateCrdsForDefinition(ctx context.Context, m string, xrd *definition, outputFolder string, cfg *config, generators []generatorFunc) error {
+	start := time.Now()
+	var frs []*fileReport
+	record := func(err error) error {
+		for _, fr := range frs {
+			cfg.report.record(fr, time.Since(start), err)
+			cfg.sarif.addFileReport(fr, err)
+		}
+		return err
+	}
+
+	output := filepath.Join(outputFolder, xrd.GetName()+"."+cfg.format)
+	if upToDateOutput(output, m, cfg) {
+		generate := func(xrd *definition, generator generatorFunc) (*extv1.CustomResourceDefinition, []byte, error) {
+			fr := &fileReport{Input: m, Status: FileStatusUpToDate}
+			frs = append(frs, fr)
+			crd, err := generator(xrd)
+			switch {
+			case crd != nil:
+				fr.CRD = crd.GetName()
+			case err == nil:
+				fr.Status = FileStatusSkipped
+			}
+			return crd, nil, err
+		}
+		crds, err := emitCRDs(xrd, generators, generate, func(*extv1.CustomResourceDefinition, []byte) error { return nil })
+		if err != nil {
+			return record(err)
+		}
+		return record(keepUpToDate(output, xrd, crds, outputFolder, cfg))
+	}
+
+	render := func(xrd *definition, generator generatorFunc) (*extv1.CustomResourceDefinition, []byte, error) {
+		fr := &fileReport{Input: m}
+		frs = append(frs, fr)
+		crd, y, err := renderCrd(ctx, m, xrd, cfg, generator, fr)
+		if crd == nil && err == nil {
+			fr.Status = FileStatusSkipped
+		}
+		return crd, y, err
+	}
+	buf := &bytes.Buffer{}
+	crds, err := emitCRDs(xrd, generators, render, streamTo(buf, cfg.format))
+	if err != nil {
+		return record(err)
+	}
+
+	if cfg.outputMode == OutputStdout {
+		for _, fr := range frs {
+			fr.Output = "-"
+		}
+		_, err := os.Stdout.Write(buf.Bytes())
+		return record(err)
+	}
+
+	if err := writeOutput(ctx, m, output, buf.Bytes(), cfg, frs[0]); err != nil {
+		return record(err)
+	}
+	// The CRDs share one output file, which is only reported once.
+	for _, fr := range frs[1:] {
+		fr.Status = frs[0].Status
+	}
+
+	if cfg.webhook != nil && cfg.dryRun == "" {
+		for _, crd := range crds {
+			if err := writeWebhook(crd, filepath.Dir(outputFolder), cfg.webhook); err != nil {
+				return record(err)
+			}
+		}
+	}
+	return record(nil)
+}
+
+// renderCrd derives a CRD from the supplied definition, read from the supplied
+// path, using the supplied generator, checks it, and returns it along with its
+// YAML encoding. It returns a nil CRD if the generator skipped the definition.
+func renderCrd(ctx context.Context, m string, xrd *definition, cfg *config, generator generatorFunc, fr *fileReport) (*extv1.CustomResourceDefinition, []byte, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, nil, err
+	}
+	cfg.log.Infof("Converting %s", m)
+
+	crd, err := generator(xrd)
 	if err != nil {
-		fmt.Printf("Error finding generator %s", err)
+		return nil, nil, err
+	}
+	if crd == nil {
+		return nil, nil, nil
+	}
+	names := make([]string, len(crd.Spec.Versions))
+	for i, v := range crd.Spec.Versions {
+		names[i] = v.Name
+	}
+	cfg.log.Infof("Generated %s with %d version(s): %s", crd.GetName(), len(names), strings.Join(names, ", "))
+	crd.Kind = "CustomResourceDefinition"
+	crd.APIVersion = "apiextensions.k8s.io/v1"
+
+	if !cfg.allowDangerous {
+		if err := checkDangerousTypes(xrd.CompositeResourceDefinition); err != nil {
+			return nil, nil, err
+		}
+	}
+	fr.CRD = crd.GetName()
+
+	if err := cfg.recordGenerated(crd, xrd); err != nil {
+		return nil, nil, err
+	}
+
+	for _, path := range stripPaths(crd, cfg.stripPaths) {
+		fr.warn("%s has no field %q to strip", crd.GetName(), path)
+	}
+
+	for _, f := range overriddenStatusFields(xrd.CompositeResourceDefinition) {
+		if cfg.strict {
+			return nil, nil, errors.Errorf(errFmtOverridden, crd.GetName(), f)
+		}
+		fr.warn("%s replaces user-defined field %s with Crossplane's", crd.GetName(), f)
+	}
+
+	for _, w := range versionWarnings(crd) {
+		fr.warn("%s", w)
+	}
+	if !servesAnyVersion(crd) {
+		fr.warn("%s: %s serves no version; set served: true on a version or pass --serve-all", m, crd.GetName())
+	}
+
+	if cfg.patches != nil {
+		if err := cfg.patches.apply(crd); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cfg.validate {
+		if err := validateCRD(crd); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cfg.selfValidate {
+		if err := selfValidate(ctx, crd); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	y, err := encodeCRD(crd, cfg.format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.commentDescriptions {
+		y, err = commentDescriptions(y)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cfg.reportSize {
+		reportSize(crd.GetName(), len(y), cfg.sizeLimit)
+	}
+
+	return crd, y, nil
+}
+
+// upToDateOutput returns true if the supplied output of the definition at path
+// m is newer than the definition, so that it is not regenerated unless forced.
+// Merged definitions may have several sources, so are always regenerated.
+func upToDateOutput(output, m string, cfg *config) bool {
+	if cfg.force || cfg.merged != nil || cfg.dryRun != "" || cfg.outputMode != OutputFiles {
+		return false
+	}
+	if cfg.gzip {
+		output += ".gz"
+	}
+	return upToDate(output, m)
+}
+
+// keepUpToDate leaves the supplied up to date output of the supplied CRDs as
+// it is. Their webhook stubs are compared to their own output, so that they
+// are written even if the CRDs are up to date.
+func keepUpToDate(output string, xrd *definition, crds []*extv1.CustomResourceDefinition, outputFolder string, cfg *config) error {
+	if cfg.gzip {
+		output += ".gz"
 	}
-	definitionFile = "test.yaml"
-	err = generateCrdsForPattern(definitionFile, cwd)
+	cfg.outputs = append(cfg.outputs, output)
+	cfg.log.Infof("%s is up to date", output)
+	for _, crd := range crds {
+		if err := cfg.recordGenerated(crd, xrd); err != nil {
+			return err
+		}
+		if cfg.webhook != nil {
+			if err := writeWebhook(crd, filepath.Dir(outputFolder), cfg.webhook); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
+// writeOutput writes y, generated from the definition at path m, to the
+// supplied output file, compressing it if requested. It only reports what
+// would change in a dry run, and leaves output that is unchanged untouched.
+func writeOutput(ctx context.Context, m string, output string, y []byte, cfg *config, fr *fileReport) error {
+	if cfg.gzip {
+		output += ".gz"
+		var err error
+		y, err = gzipBytes(y)
+		if err != nil {
+			return err
+		}
+	}
+	cfg.outputs = append(cfg.outputs, output)
+
+	if cfg.dryRun != "" {
+		changed, err := dryRun(output, y, cfg.dryRun)
+		if changed {
+			cfg.dryRunChanges++
+		}
+		return err
+	}
+
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	// Leave identical outputs untouched, so that their mtimes don't
+	// invalidate the caches of downstream builds.
+	if unchanged(output, y) {
+		cfg.log.Infof("%s is unchanged", output)
+		fr.Status = FileStatusUpToDate
+		return nil
+	}
+
+	// Name templates may place outputs in subdirectories.
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(output, y, 0644); err != nil {
+		return err
+	}
+	cfg.log.Infof("%s written", output)
+	fr.Output = output
+	return nil
+}
+
+func findPathsForPattern(ctx context.Context, pattern string, cwd string) ([]string, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	iGlob := filepath.Join(cwd, "*/", pattern)
+	ml, err := filepath.Glob(iGlob)
 	if err != nil {
-		fmt.Printf("Error finding generator %s", err)
+		return nil, err
+	}
+
+	return ml, nil
+}
+
+// findPathsForPatterns returns the union of the paths matching each of the
+// supplied patterns, without duplicates. Paths are matched one directory
+// beneath cwd, or at any depth if recursive is true.
+func findPathsForPatterns(ctx context.Context, patterns []string, cwd string, recursive bool) ([]string, error) {
+	find := findPathsForPattern
+	if recursive {
+		find = findPathsRecursively
+	}
+	var paths []string
+	for _, pattern := range patterns {
+		ml, err := find(ctx, pattern, cwd)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, ml...)
 	}
+	return dedupe(paths), nil
+}
 
+func generateCrdsForPatterns(ctx context.Context, patterns []string, cwd string, cfg *config) error {
+	ml, err := findPathsForPatterns(ctx, patterns, cwd, cfg.recursive)
+	if err != nil {
+		return err
+	}
+	return generateCrdsForFiles(ctx, ml, cwd, cfg)
+}
+
+// generateCrdsForArgs generates CRDs from the definition files named by the
+// supplied arguments, bypassing pattern discovery.
+func generateCrdsForArgs(ctx context.Context, args []string, cfg *config) error {
+	if len(args) == 0 {
+		return errors.New(errNoDefinitionFiles)
+	}
+	ml := make([]string, 0, len(args))
+	for _, a := range args {
+		fi, err := os.Stat(a)
+		if err != nil {
+			return errors.Wrapf(err, errFmtStat, a)
+		}
+		if fi.IsDir() {
+			return errors.Errorf(errFmtDefinitionFileIsDir, a)
+		}
+		// Output file names are derived relative to the input directory,
+		// which is absolute.
+		p, err := filepath.Abs(a)
+		if err != nil {
+			return err
+		}
+		ml = append(ml, p)
+	}
+	return generateCrdsForFiles(ctx, dedupe(ml), cfg.input, cfg)
+}
+
+// generateCrdsForFiles generates CRDs from the definitions at the supplied
+// paths, beneath cwd, after applying any filters the config specifies.
+func generateCrdsForFiles(ctx context.Context, ml []string, cwd string, cfg *config) error {
+	var err error
+	cfg.report.addInputs(ml)
+
+	if cfg.onlyChanged {
+		changed, ok, err := changedFiles(ctx, cwd, cfg.base)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ml = filterChanged(ml, changed)
+		} else {
+			cfg.log.Infof("Not a git repository, converting all definitions")
+		}
+	}
+
+	if !cfg.since.IsZero() {
+		ml, err = filterModifiedSince(ml, cfg.since)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.interactive {
+		ml, err = selectPathsInteractively(ml, cfg.log)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.mergeBy != "" {
+		ml, cfg.merged, err = mergeDefinitions(ml)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.lint {
+		return lintDefinitions(ctx, ml, cfg)
+	}
+
+	if cfg.reportUnused {
+		dir := cfg.compositionsDir
+		if dir == "" {
+			dir = cwd
+		}
+		return reportUnusedDefinitions(ctx, ml, dir, cfg)
+	}
+
+	err = generateCrdForPaths(ctx, ml, cfg.output, cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.emitKustomization {
+		if err := writeKustomization(cfg.output, cfg.outputs, cfg); err != nil {
+			return err
+		}
+	}
+
+	if cfg.push != "" && cfg.dryRun == "" {
+		return pushPackage(ctx, ml, cfg)
+	}
+
+	return nil
+}
+
+// checkContext returns an error if the supplied context is done, describing a
+// timeout rather than a bare deadline error.
+func checkContext(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.New(errTimeout)
+	}
+	return err
+}
+
+// config holds the command line configuration of a conversion run.
+type config struct {
+	patterns   stringsFlag
+	input      string
+	output     string
+	stdout     bool
+	verbose    bool
+	log        Logger
+	outputMode string
+	format     string
+	singleFile bool
+	recursive  bool
+	watch      bool
+
+	nameTemplateFlag string
+	nameTemplate     *template.Template
+
+	continueOnError bool
+	failures        *batchError
+
+	onlyChanged bool
+	interactive bool
+	base        string
+	sinceFlag   string
+	since       time.Time
+	reportSize  bool
+	sizeLimit   int
+
+	compositeCategories  stringsFlag
+	claimCategories      stringsFlag
+	compositeShortNames  stringsFlag
+	claimShortNames      stringsFlag
+	compositeAnnotations mapFlag
+	claimAnnotations     mapFlag
+	labels               mapFlag
+
+	patchFile      string
+	patches        *patchSet
+	stripPaths     stringsFlag
+	baseSchemaFile string
+	baseSchema     *extv1.JSONSchemaProps
+	columnsFile    string
+	enumMapFile    string
+	enumMapping    map[string]string
+	columns        []extv1.CustomResourceColumnDefinition
+
+	argoCD           bool
+	minimalStatus    bool
+	omitUpdatePolicy bool
+	validate         bool
+	allowDangerous   bool
+	dryRun           dryRunFlag
+	dryRunChanges    int
+	strict           bool
+	selfValidate     bool
+	gzip             bool
+	maxNameLength    int64
+	groupSuffix      string
+	force            bool
+	inferClaimNames  string
+	requireAll       bool
+	dumpIntermediate bool
+	storageVersion   string
+	versions         stringsFlag
+	serveAll         bool
+	configFile       string
+	deletePolicy     string
+	exclusiveRefs    bool
+	minKubeVersion   string
+
+	commentDescriptions bool
+	emitKustomization   bool
+
+	webhookService string
+	webhookPath    string
+	webhook        *webhookService
+
+	conversionService string
+	conversionPath    string
+	conversion        *webhookService
+
+	scaleSpecReplicasPath   string
+	scaleStatusReplicasPath string
+	scaleLabelSelectorPath  string
+
+	timeout time.Duration
+
+	reportUnused    bool
+	compositionsDir string
+
+	lint      bool
+	lintRules stringsFlag
+	fix       bool
+
+	mergeBy string
+	merged  map[string][]*definition
+
+	// generated maps the plural.group of each CRD generated by this run to
+	// the definition it was generated from.
+	generated map[string]string
+	// outputs holds the path of every file this run generated CRDs to, whether
+	// or not it had to be written.
+	outputs []string
+
+	reportFile string
+	report     *runReport
+
+	sarifFile string
+	sarif     *sarifLog
+
+	push        string
+	packageName string
+}
+
+// recordGenerated records that the supplied definition generated the supplied
+// CRD. It returns an error if another definition of the run already generated
+// it. CRDs are identified, and their output files named, by group and plural.
+func (c *config) recordGenerated(crd *extv1.CustomResourceDefinition, xrd *definition) error {
+	resource := crd.Spec.Names.Plural + "." + crd.Spec.Group
+	if src, ok := c.generated[resource]; ok && src != xrd.source() {
+		return errors.Errorf(errFmtDuplicateCRD, resource, src, xrd.source())
+	}
+	c.generated[resource] = xrd.source()
+	return nil
+}
+
+// load returns the definitions in the file at the supplied path, or the merged
+// definition the path stands for when definitions are merged.
+func (c *config) load(path string) ([]*definition, error) {
+	if xrds, ok := c.merged[path]; ok {
+		return xrds, nil
+	}
+	return loadXrds(path)
+}
+
+func main() {
+	cfg := &config{outputMode: OutputFiles}
+	flag.StringVar(&cfg.input, "input", "", "Directory whose subdirectories hold the definitions to convert. Defaults to the working directory.")
+	flag.StringVar(&cfg.output, "output", "", "Directory to write CRDs to, created if missing. Webhooks and intermediate definitions are written alongside it. Defaults to crds in the input directory.")
+	flag.BoolVar(&cfg.singleFile, "single-file", false, "Write the CRDs derived from each definition to one multi-document file named after the definition.")
+	flag.BoolVar(&cfg.continueOnError, "continue-on-error", false, "Convert every definition even if some fail, and report all failures at the end.")
+	flag.BoolVar(&cfg.verbose, "verbose", false, "Log each converted file to standard error.")
+	flag.BoolVar(&cfg.stdout, "stdout", false, "Print generated CRDs to standard output as a YAML stream instead of writing them to files.")
+	flag.StringVar(&cfg.nameTemplateFlag, "name-template", defaultNameTemplate, "Go template of the path of each output file, relative to the output directory. Fields are .Group, .Plural, .Kind, .Scope, .Ext and .Dir, the definition's directory relative to the input directory.")
+	flag.StringVar(&cfg.format, "format", FormatYAML, "Format of generated CRDs, yaml or json. Output files take its name as their extension.")
+	flag.BoolVar(&cfg.watch, "watch", false, "After converting, keep watching the input directory and regenerate CRDs whenever a definition is created or modified, until interrupted.")
+	flag.BoolVar(&cfg.recursive, "recursive", false, "Search for definitions at any depth beneath the input directory, rather than only one directory deep.")
+	flag.Var(&cfg.patterns, "pattern", "File name pattern of definitions to convert. May be repeated. Defaults to xrd.yaml and test.yaml.")
+	flag.BoolVar(&cfg.onlyChanged, "only-changed", false, "Only convert definitions that changed according to git diff, or that git does not track yet.")
+	flag.BoolVar(&cfg.interactive, "interactive", false, "List the discovered definitions and ask which of them to convert.")
+	flag.StringVar(&cfg.base, "base", "HEAD", "Git revision that --only-changed diffs against. Use e.g. origin/main...HEAD to diff against the merge base with a branch.")
+	flag.StringVar(&cfg.sinceFlag, "since", "", "Only convert definitions modified after this date or RFC 3339 timestamp.")
+	flag.BoolVar(&cfg.reportSize, "report-size", false, "Print the serialized size of each generated CRD.")
+	flag.IntVar(&cfg.sizeLimit, "size-limit", defaultSizeLimit, "Size in bytes above which --report-size warns about a generated CRD.")
+	flag.Var(&cfg.compositeCategories, "composite-category", "Additional category for composite resource CRDs. May be repeated.")
+	flag.Var(&cfg.claimCategories, "claim-category", "Additional category for composite resource claim CRDs. May be repeated.")
+	flag.Var(&cfg.compositeShortNames, "composite-short-name", "Additional short name for composite resource CRDs. May be repeated.")
+	flag.Var(&cfg.claimShortNames, "claim-short-name", "Additional short name for composite resource claim CRDs. May be repeated.")
+	flag.Var(&cfg.compositeAnnotations, "composite-annotations", "Annotations, as key=value pairs, for composite resource CRDs. May be repeated.")
+	flag.Var(&cfg.claimAnnotations, "claim-annotations", "Annotations, as key=value pairs, for composite resource claim CRDs. May be repeated.")
+	flag.Var(&cfg.labels, "label", "Label, as a key=value pair, for all generated CRDs. Takes precedence over labels copied from the definition. May be repeated.")
+	flag.StringVar(&cfg.patchFile, "patch", "", "YAML file of RFC 6902 JSON patches keyed by generated CRD name.")
+	flag.Var(&cfg.stripPaths, "strip-path", "Dot separated path of a field to remove from generated schemas, e.g. spec.parameters.secret. May be repeated.")
+	flag.StringVar(&cfg.baseSchemaFile, "base-schema", "", "OpenAPI v3 schema whose spec and status properties are merged into every version.")
+	flag.StringVar(&cfg.columnsFile, "printer-columns", "", "YAML list of printer columns added to every version.")
+	flag.StringVar(&cfg.enumMapFile, "enum-map", "", "YAML file mapping old enum values to new ones, applied to the enums and defaults of user fields.")
+	flag.BoolVar(&cfg.argoCD, "argocd", false, "Annotate generated CRDs with the sync options ArgoCD needs to apply them.")
+	flag.BoolVar(&cfg.minimalStatus, "minimal-status", false, "Inject only status conditions into versions that define no status.")
+	flag.BoolVar(&cfg.omitUpdatePolicy, "omit-composition-update-policy", false, "Omit the injected compositionUpdatePolicy spec field.")
+	flag.Int64Var(&cfg.maxNameLength, "max-name-length", 0, "Constrain the metadata schema to names of at most this length. Zero leaves metadata unconstrained.")
+	flag.StringVar(&cfg.groupSuffix, "group-suffix", "", "Suffix appended to the API group of generated CRDs, e.g. -dev.")
+	flag.BoolVar(&cfg.force, "force", false, "Regenerate CRDs even if they are newer than their definitions.")
+	flag.StringVar(&cfg.inferClaimNames, "infer-claim-names", "", "Derive claim names for definitions that have none using a convention: strip-x, strip-composite or claim-suffix.")
+	flag.BoolVar(&cfg.requireAll, "require-all-user-fields", false, "Require every spec field the definition declares. Injected Crossplane fields stay optional.")
+	flag.BoolVar(&cfg.dumpIntermediate, "dump-intermediate", false, "Write each definition as parsed, before conversion, to the intermediate directory.")
+	flag.StringVar(&cfg.scaleSpecReplicasPath, "scale-spec-replicas-path", "", "Enable the scale subresource with this spec replicas path, e.g. .spec.replicas.")
+	flag.StringVar(&cfg.scaleStatusReplicasPath, "scale-status-replicas-path", "", "Status replicas path of the scale subresource, e.g. .status.replicas.")
+	flag.StringVar(&cfg.scaleLabelSelectorPath, "scale-label-selector-path", "", "Optional label selector path of the scale subresource.")
+	flag.BoolVar(&cfg.exclusiveRefs, "exclusive-composition-refs", false, "Add a CEL validation rule forbidding both compositionRef and compositionSelector. Requires --min-kube-version "+minCELKubeVersion+" or later.")
+	flag.StringVar(&cfg.minKubeVersion, "min-kube-version", "", "Oldest Kubernetes version the generated CRDs must support, e.g. 1.25.")
+	flag.StringVar(&cfg.configFile, "config", "", "YAML config file of defaults, such as compositeDeletePolicy. Flags and environment variables override it.")
+	flag.StringVar(&cfg.deletePolicy, "composite-delete-policy", "", "Default compositeDeletePolicy of claim CRDs; Background or Foreground. Overrides $"+EnvCompositeDeletePolicy+" and the config file.")
+	flag.StringVar(&cfg.storageVersion, "storage-version", "", "Version to store, overriding the referenceable version.")
+	flag.BoolVar(&cfg.serveAll, "serve-all", false, "Serve every version of each generated CRD, whether or not the definition serves it.")
+	flag.Var(&cfg.versions, "versions", "Comma separated versions of each definition to generate. Defaults to all versions.")
+	flag.BoolVar(&cfg.commentDescriptions, "comment-descriptions", false, "Render field descriptions as YAML comments next to their fields.")
+	flag.StringVar(&cfg.webhookService, "webhook-service", "", "Also write a ValidatingWebhookConfiguration stub for each CRD, calling this namespace/name service.")
+	flag.StringVar(&cfg.webhookPath, "webhook-path", "/validate", "Path on the webhook service to call. Used with --webhook-service.")
+	flag.StringVar(&cfg.conversionService, "conversion-webhook-service", "", "Convert between the versions of each CRD by calling this namespace/name service, rather than not converting them.")
+	flag.StringVar(&cfg.conversionPath, "conversion-webhook-path", "/convert", "Path on the conversion webhook service to call. Used with --conversion-webhook-service.")
+	flag.BoolVar(&cfg.validate, "validate", false, "Check generated CRDs for problems, such as incomplete printer columns, before writing them.")
+	flag.BoolVar(&cfg.allowDangerous, "allow-dangerous-types", false, "Allow schemas that preserve unknown fields at their root, spec or status, or allow arbitrary additional properties.")
+	flag.Var(&cfg.dryRun, "dry-run", "Report what would be written without writing anything, exiting non-zero if a CRD is invalid or would change. One of summary (the default) or diff, which also prints a diff of each change.")
+	flag.BoolVar(&cfg.strict, "strict", false, "Fail instead of warning when a user-defined status field is replaced by Crossplane's.")
+	flag.BoolVar(&cfg.selfValidate, "self-validate", false, "Validate generated CRDs as the API server would before writing them.")
+	flag.BoolVar(&cfg.gzip, "gzip", false, "Write each generated CRD gzip compressed to a .yaml.gz file.")
+	flag.BoolVar(&cfg.emitKustomization, "emit-kustomization", false, "Write a kustomization.yaml listing the generated CRDs to the output directory, replacing any existing one.")
+	flag.DurationVar(&cfg.timeout, "timeout", 0, "Maximum duration of the whole run, e.g. 30s. Zero means no timeout.")
+	flag.BoolVar(&cfg.reportUnused, "report-unused-definitions", false, "Report definitions that no composition references instead of converting them, failing if there are any.")
+	flag.StringVar(&cfg.compositionsDir, "compositions-dir", "", "Directory searched for compositions by --report-unused-definitions. Defaults to the working directory.")
+	flag.BoolVar(&cfg.lint, "lint", false, "Report best practice problems in definitions instead of converting them.")
+	flag.Var(&cfg.lintRules, "lint-rules", "Lint rules or rule sets (all, docs, schema, ux) to run. May be repeated. Defaults to all.")
+	flag.BoolVar(&cfg.fix, "fix", false, "With --lint, add a TODO description to fields that have none, backing up each changed definition to a .bak file.")
+	flag.StringVar(&cfg.mergeBy, "merge-by", "", "Merge definitions sharing a group and kind before converting them, later files taking precedence. Only group-kind is supported.")
+	flag.StringVar(&cfg.sarifFile, "sarif", "", "Write lint and conversion findings to this file as a SARIF report.")
+	flag.StringVar(&cfg.reportFile, "report", "", "Write a JSON report summarizing the run to this file.")
+	flag.StringVar(&cfg.push, "push", "", "Push a Configuration package of the definitions, and compositions under --compositions-dir, to this reference, e.g. oci://registry/repo:tag.")
+	flag.StringVar(&cfg.packageName, "package-name", "", "Name of the Configuration pushed by --push. Defaults to the repository name.")
+	flag.Parse()
+	cfg.log = NewLogger(os.Stderr, cfg.verbose)
+
+	// The report is always kept for the summary, but only written on request.
+	cfg.report = newRunReport()
+	if cfg.sarifFile != "" {
+		cfg.sarif = newSarifLog()
+	}
+
+	if len(cfg.patterns) == 0 {
+		cfg.patterns = stringsFlag{"xrd.yaml", "test.yaml"}
+	}
+
+	if cfg.mergeBy != "" && cfg.mergeBy != MergeByGroupKind {
+		cfg.log.Errorf(errFmtUnknownMergeBy, cfg.mergeBy)
+		os.Exit(1)
+	}
+
+	if err := checkFormat(cfg.format); err != nil {
+		cfg.log.Errorf("%s", err)
+		os.Exit(1)
+	}
+	if cfg.emitKustomization && cfg.stdout {
+		cfg.log.Errorf(errKustomizationStdout)
+		os.Exit(1)
+	}
+	if cfg.emitKustomization && cfg.gzip {
+		cfg.log.Errorf(errKustomizationGzip)
+		os.Exit(1)
+	}
+	if err := checkFormatOptions(cfg); err != nil {
+		cfg.log.Errorf("%s", err)
+		os.Exit(1)
+	}
+	if err := checkOutputOptions(cfg); err != nil {
+		cfg.log.Errorf("%s", err)
+		os.Exit(1)
+	}
+
+	if err := checkLabels(cfg.labels); err != nil {
+		cfg.log.Errorf("%s", err)
+		os.Exit(1)
+	}
+
+	nt, err := parseNameTemplate(cfg.nameTemplateFlag)
+	if err != nil {
+		cfg.log.Errorf("%s", err)
+		os.Exit(1)
+	}
+	cfg.nameTemplate = nt
+
+	if cfg.sinceFlag != "" {
+		since, err := parseSince(cfg.sinceFlag)
+		if err != nil {
+			cfg.log.Errorf("%s", err)
+			os.Exit(1)
+		}
+		cfg.since = since
+	}
+
+	ctx := context.Background()
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		cfg.log.Errorf("%s", err)
+	}
+	if cfg.input == "" {
+		cfg.input = cwd
+	}
+	if cfg.output == "" {
+		cfg.output = filepath.Join(cfg.input, "crds")
+	}
+
+	if flag.Arg(0) == "validate" {
+		if err := validateDefinitions(ctx, os.Stdout, flag.Args()[1:], cfg); err != nil {
+			cfg.log.Errorf("Error validating definitions %s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "gen" && cfg.watch {
+		cfg.log.Errorf(errGenWatch)
+		os.Exit(1)
+	}
+
+	if flag.Arg(0) == "print-schema" {
+		if err := printSchema(os.Stdout, flag.Args()[1:], cfg); err != nil {
+			cfg.log.Errorf("Error printing schema %s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fc, err := loadFileConfig(cfg.configFile)
+	if err != nil {
+		cfg.log.Errorf("Error loading config file %s", err)
+		os.Exit(1)
+	}
+	cfg.deletePolicy, err = resolveCompositeDeletePolicy(cfg.deletePolicy, fc)
+	if err != nil {
+		cfg.log.Errorf("Error resolving composite delete policy %s", err)
+		os.Exit(1)
+	}
+
+	if cfg.patchFile != "" {
+		cfg.patches, err = loadPatches(cfg.patchFile)
+		if err != nil {
+			cfg.log.Errorf("Error loading patches %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.columnsFile != "" {
+		cfg.columns, err = loadPrinterColumns(cfg.columnsFile)
+		if err != nil {
+			cfg.log.Errorf("Error loading printer columns %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.enumMapFile != "" {
+		cfg.enumMapping, err = loadEnumMapping(cfg.enumMapFile)
+		if err != nil {
+			cfg.log.Errorf("Error loading enum mapping %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.baseSchemaFile != "" {
+		cfg.baseSchema, err = loadBaseSchema(cfg.baseSchemaFile)
+		if err != nil {
+			cfg.log.Errorf("Error loading base schema %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.exclusiveRefs {
+		if err := checkCELSupported(cfg.minKubeVersion); err != nil {
+			cfg.log.Errorf("Error enabling exclusive composition refs %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.webhookService != "" {
+		cfg.webhook, err = parseWebhookService(cfg.webhookService, cfg.webhookPath)
+		if err != nil {
+			cfg.log.Errorf("Error parsing webhook service %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.conversionService != "" {
+		cfg.conversion, err = parseWebhookService(cfg.conversionService, cfg.conversionPath)
+		if err != nil {
+			cfg.log.Errorf("Error parsing conversion webhook service %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if flag.Arg(0) == "-" {
+		cfg.outputMode = OutputStdout
+		messages = os.Stderr
+		if err := convertStream(ctx, os.Stdin, os.Stdout, cfg); err != nil {
+			cfg.log.Errorf("Error converting standard input %s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := checkDir("input", cfg.input); err != nil {
+		cfg.log.Errorf("Error checking directories %s", err)
+		os.Exit(1)
+	}
+	if cfg.stdout {
+		cfg.outputMode = OutputStdout
+		messages = os.Stderr
+	}
+	if cfg.outputMode == OutputFiles && cfg.dryRun == "" {
+		if err := ensureDir("output", cfg.output); err != nil {
+			cfg.log.Errorf("Error checking directories %s", err)
+			os.Exit(1)
+		}
+	}
+	if flag.Arg(0) == "gen" {
+		err = generateCrdsForArgs(ctx, flag.Args()[1:], cfg)
+	} else {
+		err = generateCrdsForPatterns(ctx, cfg.patterns, cfg.input, cfg)
+	}
+
+	if err != nil {
+		cfg.log.Errorf("Error finding generator %s", err)
+		cfg.report.fail(err)
+	}
+
+	if cfg.watch {
+		if err := watchDefinitions(ctx, cfg); err != nil {
+			cfg.log.Errorf("Error watching definitions %s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.patches != nil {
+		for _, name := range cfg.patches.unapplied() {
+			w := fmt.Sprintf("patch target %q does not match any generated CRD", name)
+			fmt.Fprintf(messages, "Warning: %s\n", w)
+			cfg.report.warn(w)
+		}
+	}
+
+	if cfg.reportFile != "" {
+		if err := cfg.report.write(cfg.reportFile); err != nil {
+			cfg.log.Errorf("%s", err)
+		}
+	}
+
+	if cfg.sarif != nil {
+		if err := cfg.sarif.write(cfg.sarifFile); err != nil {
+			cfg.log.Errorf("%s", err)
+		}
+	}
+
+	c := cfg.report.Counts
+	fmt.Fprintf(messages, "Summary: %d CRDs succeeded, %d failed, %d skipped\n", c.Converted+c.UpToDate, c.Failed, c.Skipped)
+
+	if err != nil || (cfg.dryRun != "" && cfg.dryRunChanges > 0) {
+		os.Exit(1)
+	}
 }
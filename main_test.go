@@ -0,0 +1,957 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testConfig returns the configuration of a quiet conversion run.
+func testConfig() *config {
+	nt, err := parseNameTemplate(defaultNameTemplate)
+	if err != nil {
+		panic(err)
+	}
+	return &config{
+		outputMode:   OutputFiles,
+		format:       FormatYAML,
+		nameTemplate: nt,
+		log:          NewLogger(ioutil.Discard, false),
+		report:       newRunReport(),
+		generated:    map[string]string{},
+	}
+}
+
+// compositeGenerator derives composite resource CRDs using the supplied
+// options.
+func compositeGenerator(opts ...Option) generatorFunc {
+	return func(xrd *definition) (*extv1.CustomResourceDefinition, error) {
+		return ForCompositeResource(xrd.CompositeResourceDefinition, xrd.compositeOptions(opts)...)
+	}
+}
+
+func TestRenderCrdDuplicates(t *testing.T) {
+	renamed := strings.Replace(baseXRD, "name: xthings.example.org", "name: other.example.org", 1)
+
+	cases := map[string]struct {
+		reason  string
+		files   [][2]string
+		wantErr string
+	}{
+		"DistinctCRDs": {
+			reason: "Definitions of different CRDs should not collide.",
+			files: [][2]string{
+				{"a/xrd.yaml", baseXRD},
+				{"b/xrd.yaml", strings.NewReplacer("XThing", "XOther", "xthings", "xothers").Replace(baseXRD)},
+			},
+		},
+		"CollidingFiles": {
+			reason: "Definitions in different files colliding on group and plural should be reported.",
+			files: [][2]string{
+				{"a/xrd.yaml", baseXRD},
+				{"b/xrd.yaml", renamed},
+			},
+			wantErr: `CRD "xthings.example.org" is generated by both`,
+		},
+		"CollidingDocuments": {
+			reason: "Definitions in the same file colliding on group and plural should be reported.",
+			files: [][2]string{
+				{"a/xrd.yaml", baseXRD + "---\n" + renamed},
+			},
+			wantErr: `xrd.yaml (document 2)`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var all []*definition
+			for _, p := range writeFiles(t, t.TempDir(), tc.files...) {
+				xrds, err := loadXrds(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				all = append(all, xrds...)
+			}
+
+			cfg := testConfig()
+			var err error
+			for _, xrd := range all {
+				if _, _, err = renderCrd(context.Background(), xrd.path, xrd, cfg, compositeGenerator(), &fileReport{}); err != nil {
+					break
+				}
+			}
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("\n%s\nrenderCrd(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("\n%s\nrenderCrd(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateCrdForPathsUpToDate(t *testing.T) {
+	const stale = "stale\n"
+
+	cases := map[string]struct {
+		reason      string
+		age         time.Duration
+		force       bool
+		singleFile  bool
+		webhook     bool
+		wantWritten bool
+	}{
+		"UpToDate": {
+			reason: "An output newer than its definition should not be regenerated.",
+			age:    -time.Hour,
+		},
+		"Stale": {
+			reason:      "An output older than its definition should be regenerated.",
+			age:         time.Hour,
+			wantWritten: true,
+		},
+		"Force": {
+			reason:      "An output newer than its definition should be regenerated when forced.",
+			age:         -time.Hour,
+			force:       true,
+			wantWritten: true,
+		},
+		"UpToDateWebhook": {
+			reason:  "A missing webhook stub should be written even if the CRD is up to date.",
+			age:     -time.Hour,
+			webhook: true,
+		},
+		"UpToDateSingleFile": {
+			reason:     "A single output file newer than its definition should not be regenerated.",
+			age:        -time.Hour,
+			singleFile: true,
+			webhook:    true,
+		},
+		"StaleSingleFile": {
+			reason:      "A single output file older than its definition should be regenerated.",
+			age:         time.Hour,
+			singleFile:  true,
+			wantWritten: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			defer func(w io.Writer) { messages = w }(messages)
+			messages = buf
+
+			dir := t.TempDir()
+			m := writeFiles(t, dir, [2]string{"a/xrd.yaml", baseXRD})[0]
+			name := "crds/example.org_xthings.yaml"
+			if tc.singleFile {
+				name = "crds/xthings.example.org.yaml"
+			}
+			output := writeFiles(t, dir, [2]string{name, stale})[0]
+			now := time.Now()
+			if err := os.Chtimes(m, now, now); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(output, now.Add(-tc.age), now.Add(-tc.age)); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := testConfig()
+			cfg.input = dir
+			cfg.force = tc.force
+			cfg.singleFile = tc.singleFile
+			cfg.reportSize = true
+			if tc.webhook {
+				cfg.webhook = &webhookService{Namespace: "ns", Name: "svc"}
+			}
+			if err := generateCrdForPaths(context.Background(), []string{m}, filepath.Join(dir, "crds"), cfg); err != nil {
+				t.Fatalf("\n%s\ngenerateCrdForPaths(...): %v", tc.reason, err)
+			}
+
+			y, err := ioutil.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written := string(y) != stale; written != tc.wantWritten {
+				t.Errorf("\n%s\ngenerateCrdForPaths(...): output written: %t, want %t", tc.reason, written, tc.wantWritten)
+			}
+			// Only rendered CRDs have their size reported.
+			if rendered := buf.Len() > 0; rendered != tc.wantWritten {
+				t.Errorf("\n%s\ngenerateCrdForPaths(...): CRD rendered: %t, want %t", tc.reason, rendered, tc.wantWritten)
+			}
+			_, err = os.Stat(filepath.Join(dir, "webhooks", "example.org_xthings.yaml"))
+			if stub := err == nil; stub != tc.webhook {
+				t.Errorf("\n%s\ngenerateCrdForPaths(...): webhook stub written: %t, want %t", tc.reason, stub, tc.webhook)
+			}
+		})
+	}
+}
+
+func TestShortNames(t *testing.T) {
+	xrd := strings.Replace(claimXRD, "    plural: xthings\n", "    plural: xthings\n    shortNames: [xt]\n", 1) + "    shortNames: [th]\n"
+
+	cases := map[string]struct {
+		reason        string
+		compositeOpts []Option
+		claimOpts     []Option
+		wantComposite []string
+		wantClaim     []string
+		wantErr       string
+	}{
+		"Definition": {
+			reason:        "Short names the definition declares should be kept.",
+			wantComposite: []string{"xt"},
+			wantClaim:     []string{"th"},
+		},
+		"Extra": {
+			reason:        "Extra short names should follow those the definition declares.",
+			compositeOpts: []Option{WithShortNames("xth")},
+			claimOpts:     []Option{WithShortNames("thg")},
+			wantComposite: []string{"xt", "xth"},
+			wantClaim:     []string{"th", "thg"},
+		},
+		"Duplicate": {
+			reason:        "Extra short names the definition already declares should not be repeated.",
+			compositeOpts: []Option{WithShortNames("xt")},
+			wantComposite: []string{"xt"},
+			wantClaim:     []string{"th"},
+		},
+		"ExtraCollision": {
+			reason:        "An extra composite resource short name the claim has should be rejected.",
+			compositeOpts: []Option{WithShortNames("th")},
+			wantErr:       `"th" conflicts with composite resource name`,
+		},
+		"ExtraClaimCollision": {
+			reason:    "An extra claim short name the composite resource has should be rejected.",
+			claimOpts: []Option{WithShortNames("xt")},
+			wantErr:   `"xt" conflicts with composite resource name`,
+		},
+		"ExtrasCollision": {
+			reason:        "Extra short names given to both the composite resource and claim should be rejected.",
+			compositeOpts: []Option{WithShortNames("both")},
+			claimOpts:     []Option{WithShortNames("both")},
+			wantErr:       `"both" conflicts with composite resource name`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := decodeXrd([]byte(xrd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			for _, generator := range generatorsFor(tc.compositeOpts, tc.claimOpts, nil) {
+				var crd *extv1.CustomResourceDefinition
+				if crd, err = generator(d); err != nil {
+					break
+				}
+				got = append(got, crd.Spec.Names.ShortNames)
+			}
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("\n%s\ngenerator(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("\n%s\ngenerator(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff([][]string{tc.wantComposite, tc.wantClaim}, got); diff != "" {
+				t.Errorf("\n%s\ngenerator(...): -want, +got short names:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestXValidations(t *testing.T) {
+	const rule = "self.replicas <= self.maxReplicas"
+	xrd := strings.Replace(claimXRD, `        properties:
+          spec:
+            type: object
+            properties:
+              base:
+                type: string
+`, `        x-kubernetes-validations:
+        - rule: has(self.spec)
+        properties:
+          spec:
+            type: object
+            x-kubernetes-validations:
+            - rule: `+rule+`
+              message: too many replicas
+            properties:
+              base:
+                type: string
+              replicas:
+                type: integer
+              maxReplicas:
+                type: integer
+              nested:
+                type: object
+                properties:
+                  name:
+                    type: string
+                    x-kubernetes-validations:
+                    - rule: self.size() > 0
+`, 1)
+
+	want := map[string]extv1.ValidationRules{
+		"root":        {{Rule: "has(self.spec)"}},
+		"spec":        {{Rule: rule, Message: "too many replicas"}},
+		"nested.name": {{Rule: "self.size() > 0"}},
+	}
+	generators := map[string]generatorFunc{
+		"Composite": compositeGenerator(),
+		"Claim": func(xrd *definition) (*extv1.CustomResourceDefinition, error) {
+			return ForCompositeResourceClaim(xrd.CompositeResourceDefinition)
+		},
+	}
+	for name, generator := range generators {
+		t.Run(name, func(t *testing.T) {
+			d, err := decodeXrd([]byte(xrd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd, err := generator(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root := crd.Spec.Versions[0].Schema.OpenAPIV3Schema
+			spec := root.Properties["spec"]
+			got := map[string]extv1.ValidationRules{
+				"root":        root.XValidations,
+				"spec":        spec.XValidations,
+				"nested.name": spec.Properties["nested"].Properties["name"].XValidations,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("\nCEL rules should be carried over to the CRD at every level.\n%s: -want, +got:\n%s", name, diff)
+			}
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	xrd := strings.Replace(claimXRD, "  name: xthings.example.org\n", `  name: xthings.example.org
+  labels:
+    team: platform
+  annotations:
+    example.org/owner: platform
+    example.org/tier: gold
+    xrdconvert.dev/composite-categories: infra
+`, 1)
+
+	cases := map[string]struct {
+		reason          string
+		opts            []Option
+		wantLabels      map[string]string
+		wantAnnotations map[string]string
+	}{
+		"Definition": {
+			reason:          "The definition's labels and annotations, other than this tool's own, should be copied to each CRD.",
+			wantLabels:      map[string]string{"team": "platform"},
+			wantAnnotations: map[string]string{"example.org/owner": "platform", "example.org/tier": "gold"},
+		},
+		"Merged": {
+			reason: "Labels and annotations supplied as options should be merged with, and take precedence over, the definition's.",
+			opts: []Option{
+				WithLabels(map[string]string{"env": "dev"}),
+				WithAnnotations(map[string]string{"example.org/tier": "silver"}),
+			},
+			wantLabels:      map[string]string{"team": "platform", "env": "dev"},
+			wantAnnotations: map[string]string{"example.org/owner": "platform", "example.org/tier": "silver"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := decodeXrd([]byte(xrd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, generator := range generatorsFor(tc.opts, tc.opts, nil) {
+				crd, err := generator(d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.wantLabels, crd.GetLabels()); diff != "" {
+					t.Errorf("\n%s\n%s: -want, +got labels:\n%s", tc.reason, crd.GetName(), diff)
+				}
+				if diff := cmp.Diff(tc.wantAnnotations, crd.GetAnnotations()); diff != "" {
+					t.Errorf("\n%s\n%s: -want, +got annotations:\n%s", tc.reason, crd.GetName(), diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMergePrinterColumns(t *testing.T) {
+	col := func(name, path string) extv1.CustomResourceColumnDefinition {
+		return extv1.CustomResourceColumnDefinition{Name: name, Type: "string", JSONPath: path}
+	}
+	defaults := []extv1.CustomResourceColumnDefinition{col("READY", ".status.ready"), col("AGE", ".metadata.creationTimestamp")}
+
+	cases := map[string]struct {
+		reason string
+		user   []extv1.CustomResourceColumnDefinition
+		want   []extv1.CustomResourceColumnDefinition
+	}{
+		"NoUserColumns": {
+			reason: "Without user columns the defaults should be used.",
+			want:   defaults,
+		},
+		"Distinct": {
+			reason: "User columns should precede the defaults.",
+			user:   []extv1.CustomResourceColumnDefinition{col("SIZE", ".spec.size")},
+			want:   []extv1.CustomResourceColumnDefinition{col("SIZE", ".spec.size"), col("READY", ".status.ready"), col("AGE", ".metadata.creationTimestamp")},
+		},
+		"Override": {
+			reason: "A user column should replace the default of the same name.",
+			user:   []extv1.CustomResourceColumnDefinition{col("READY", ".status.custom")},
+			want:   []extv1.CustomResourceColumnDefinition{col("READY", ".status.custom"), col("AGE", ".metadata.creationTimestamp")},
+		},
+		"OverrideCaseInsensitive": {
+			reason: "Column names should be compared as kubectl shows them, in upper case.",
+			user:   []extv1.CustomResourceColumnDefinition{col("Ready", ".status.custom")},
+			want:   []extv1.CustomResourceColumnDefinition{col("Ready", ".status.custom"), col("AGE", ".metadata.creationTimestamp")},
+		},
+		"DuplicateUserColumns": {
+			reason: "Only the first of several user columns of the same name should be kept.",
+			user:   []extv1.CustomResourceColumnDefinition{col("SIZE", ".spec.size"), col("SIZE", ".spec.other")},
+			want:   []extv1.CustomResourceColumnDefinition{col("SIZE", ".spec.size"), col("READY", ".status.ready"), col("AGE", ".metadata.creationTimestamp")},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := mergePrinterColumns(tc.user, defaults)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nmergePrinterColumns(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRenderCrdGeneratorError(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		corrupt func(xrd *v1.CompositeResourceDefinition)
+		wantErr string
+	}{
+		"Composite": {
+			reason:  "A definition the composite generator cannot convert should fail cleanly.",
+			corrupt: func(xrd *v1.CompositeResourceDefinition) { xrd.Spec.Versions = nil },
+			wantErr: "defines no versions",
+		},
+		"Claim": {
+			reason:  "A definition the claim generator cannot convert should fail cleanly.",
+			corrupt: func(xrd *v1.CompositeResourceDefinition) { xrd.Spec.ClaimNames.Kind = "thing" },
+			wantErr: errInvalidClaimNames,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xrd, err := decodeXrd([]byte(claimXRD))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.corrupt(xrd.CompositeResourceDefinition)
+
+			cfg := testConfig()
+			for _, generator := range generatorsFor(nil, nil, nil) {
+				if _, _, err = renderCrd(context.Background(), "xrd.yaml", xrd, cfg, generator, &fileReport{}); err != nil {
+					break
+				}
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("\n%s\nrenderCrd(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateClaimNamesFor(t *testing.T) {
+	composite := extv1.CustomResourceDefinitionNames{Kind: "XThing", Plural: "xthings", ShortNames: []string{"xt"}}
+	claim := func(kind, plural string, shortNames ...string) extv1.CustomResourceDefinitionNames {
+		return extv1.CustomResourceDefinitionNames{Kind: kind, Plural: plural, ShortNames: shortNames}
+	}
+
+	cases := map[string]struct {
+		reason  string
+		claim   extv1.CustomResourceDefinitionNames
+		wantErr string
+	}{
+		"Valid": {
+			reason: "Distinct, well formed claim names should be accepted.",
+			claim:  claim("Thing", "things", "th"),
+		},
+		"EmptyPlural": {
+			reason:  "An empty plural would name the CRD after its group alone.",
+			claim:   claim("Thing", ""),
+			wantErr: "claim plural must not be empty",
+		},
+		"EmptyKind": {
+			reason:  "An empty kind should be rejected.",
+			claim:   claim("", "things"),
+			wantErr: "claim kind must not be empty",
+		},
+		"KindAsPlural": {
+			reason:  "A plural copied from the kind, and so uppercase, should be rejected.",
+			claim:   claim("Thing", "Thing"),
+			wantErr: `claim plural "Thing" must be lowercase`,
+		},
+		"UppercaseSingular": {
+			reason:  "A singular that is not lowercase should be rejected.",
+			claim:   extv1.CustomResourceDefinitionNames{Kind: "Thing", Plural: "things", Singular: "Thing"},
+			wantErr: `claim singular "Thing" must be lowercase`,
+		},
+		"UppercaseShortName": {
+			reason:  "A short name that is not lowercase should be rejected.",
+			claim:   claim("Thing", "things", "TH"),
+			wantErr: `claim short name "TH" must be lowercase`,
+		},
+		"LowercaseKind": {
+			reason:  "A kind that does not start with an uppercase letter should be rejected.",
+			claim:   claim("thing", "things"),
+			wantErr: `claim kind "thing" must start with an uppercase letter`,
+		},
+		"InvalidPlural": {
+			reason:  "A plural that is not a DNS label should be rejected.",
+			claim:   claim("Thing", "my.things"),
+			wantErr: `claim plural "my.things" is not a valid DNS label`,
+		},
+		"ConflictingKind": {
+			reason:  "A kind the composite resource has should be rejected.",
+			claim:   claim("XThing", "things"),
+			wantErr: `"XThing" conflicts with composite resource name`,
+		},
+		"ConflictingShortName": {
+			reason:  "A short name the composite resource has should be rejected.",
+			claim:   claim("Thing", "things", "xt"),
+			wantErr: `"xt" conflicts with composite resource name`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateClaimNamesFor(composite, tc.claim)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("\n%s\nvalidateClaimNamesFor(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("\n%s\nvalidateClaimNamesFor(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// deriveCRDs returns the composite resource CRD and, if the supplied definition
+// offers a claim, the claim CRD derived from it using the options of cfg.
+func deriveCRDs(t *testing.T, xrd string, cfg *config) []*extv1.CustomResourceDefinition {
+	t.Helper()
+	d, err := decodeXrd([]byte(xrd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crds []*extv1.CustomResourceDefinition
+	for _, generator := range generatorsFor(cfg.compositeOptions(), cfg.claimOptions(), nil) {
+		crd, err := generator(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if crd != nil {
+			crds = append(crds, crd)
+		}
+	}
+	return crds
+}
+
+func TestTimeout(t *testing.T) {
+	slow := func(ctx context.Context) generatorFunc {
+		return func(xrd *definition) (*extv1.CustomResourceDefinition, error) {
+			<-ctx.Done()
+			return compositeGenerator()(xrd)
+		}
+	}
+
+	cases := map[string]struct {
+		reason  string
+		timeout time.Duration
+		run     func(ctx context.Context, dir string, cfg *config) error
+	}{
+		"Discovery": {
+			reason: "A run that times out before discovery should stop with a timeout error.",
+			run: func(ctx context.Context, dir string, cfg *config) error {
+				return generateCrdsForPatterns(ctx, cfg.patterns, dir, cfg)
+			},
+		},
+		"Loading": {
+			reason: "A run that times out between definitions should stop before loading the next one.",
+			run: func(ctx context.Context, dir string, cfg *config) error {
+				cfg.continueOnError = true
+				_, err := loadPaths(ctx, []string{filepath.Join(dir, "missing.yaml")}, cfg)
+				return err
+			},
+		},
+		"SlowGeneration": {
+			reason:  "A run that times out while generating should stop before writing.",
+			timeout: 10 * time.Millisecond,
+			run: func(ctx context.Context, dir string, cfg *config) error {
+				m := filepath.Join(dir, "a", "xrd.yaml")
+				xrd, err := loadXrd(m)
+				if err != nil {
+					return err
+				}
+				return generateCrdForPath(ctx, m, xrd, filepath.Join(dir, "crds"), cfg, slow(ctx), &fileReport{})
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, [2]string{"a/xrd.yaml", baseXRD})
+			cfg := testConfig()
+			cfg.input = dir
+			cfg.patterns = stringsFlag{"xrd.yaml"}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			if err := tc.run(ctx, dir, cfg); err == nil || err.Error() != errTimeout {
+				t.Errorf("\n%s\ngot error %v, want %q", tc.reason, err, errTimeout)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "crds")); !os.IsNotExist(err) {
+				t.Errorf("\n%s\noutput directory exists after timing out", tc.reason)
+			}
+		})
+	}
+}
+
+func TestEmbeddedResource(t *testing.T) {
+	xrd := strings.Replace(claimXRD, `              base:
+                type: string
+`, `              base:
+                type: string
+              template:
+                type: object
+                x-kubernetes-embedded-resource: true
+                required: [apiVersion, kind, metadata]
+                properties:
+                  apiVersion:
+                    type: string
+                  kind:
+                    type: string
+                  metadata:
+                    type: object
+                    x-kubernetes-preserve-unknown-fields: true
+`, 1)
+
+	for _, crd := range deriveCRDs(t, xrd, testConfig()) {
+		t.Run(crd.GetName(), func(t *testing.T) {
+			got := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["template"]
+			if !got.XEmbeddedResource {
+				t.Errorf("\nx-kubernetes-embedded-resource should survive conversion.\ntemplate: got %+v", got)
+			}
+			if diff := cmp.Diff([]string{"apiVersion", "kind", "metadata"}, got.Required); diff != "" {
+				t.Errorf("\nThe required fields of an embedded resource should survive conversion.\ntemplate: -want, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"apiVersion", "kind", "metadata"}, sortedKeys(got.Properties)); diff != "" {
+				t.Errorf("\nThe structure of an embedded resource should survive conversion.\ntemplate: -want, +got:\n%s", diff)
+			}
+			if err := selfValidate(context.Background(), crd); err != nil {
+				t.Errorf("\nA CRD with an embedded resource should be valid.\nselfValidate(...): %v", err)
+			}
+		})
+	}
+}
+
+func TestFindPathsForPatterns(t *testing.T) {
+	files := [][2]string{
+		{"a/xrd.yaml", baseXRD},
+		{"b/definition.yaml", baseXRD},
+		{"c/other.yaml", baseXRD},
+		{"d/e/xrd.yaml", baseXRD},
+	}
+
+	cases := map[string]struct {
+		reason    string
+		patterns  []string
+		recursive bool
+		want      []string
+	}{
+		"OnePattern": {
+			reason:   "Only files matching the pattern should be found.",
+			patterns: []string{"xrd.yaml"},
+			want:     []string{"a/xrd.yaml"},
+		},
+		"TwoPatterns": {
+			reason:   "Files matching any of the patterns should be found.",
+			patterns: []string{"xrd.yaml", "definition.yaml"},
+			want:     []string{"a/xrd.yaml", "b/definition.yaml"},
+		},
+		"RepeatedPattern": {
+			reason:   "Files matched by several patterns should be found once.",
+			patterns: []string{"xrd.yaml", "*.yaml", "xrd.yaml"},
+			want:     []string{"a/xrd.yaml", "b/definition.yaml", "c/other.yaml"},
+		},
+		"Recursive": {
+			reason:    "Files beneath nested directories should be found when recursing.",
+			patterns:  []string{"xrd.yaml"},
+			recursive: true,
+			want:      []string{"a/xrd.yaml", "d/e/xrd.yaml"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, files...)
+			paths, err := findPathsForPatterns(context.Background(), tc.patterns, dir, tc.recursive)
+			if err != nil {
+				t.Fatalf("\n%s\nfindPathsForPatterns(...): %v", tc.reason, err)
+			}
+			got := make([]string, 0, len(paths))
+			for _, p := range paths {
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nfindPathsForPatterns(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestClusterSourcedDefinition(t *testing.T) {
+	exported := strings.Replace(claimXRD, "  name: xthings.example.org\n", `  name: xthings.example.org
+  uid: 0d4f6f2e-5b1a-4c4e-9d35-1f1e0c1a2b3c
+  resourceVersion: "12345"
+  generation: 3
+  creationTimestamp: "2024-01-01T00:00:00Z"
+  labels:
+    team: platform
+  annotations:
+    example.org/owner: platform
+    kubectl.kubernetes.io/last-applied-configuration: '{"kind":"CompositeResourceDefinition"}'
+  managedFields:
+  - manager: kubectl
+    operation: Apply
+`, 1) + `status:
+  conditions:
+  - type: Established
+    status: "True"
+    reason: WatchingCompositeResource
+    lastTransitionTime: "2024-01-01T00:00:00Z"
+`
+
+	want := metav1.ObjectMeta{
+		Labels:      map[string]string{"team": "platform"},
+		Annotations: map[string]string{"example.org/owner": "platform"},
+	}
+	for i, crd := range deriveCRDs(t, exported, testConfig()) {
+		clean := deriveCRDs(t, claimXRD, testConfig())[i]
+		got := crd.ObjectMeta
+		got.Name = ""
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("\nBookkeeping metadata should not leak into the CRD.\n%s: -want, +got metadata:\n%s", crd.GetName(), diff)
+		}
+		if diff := cmp.Diff(clean.Spec, crd.Spec); diff != "" {
+			t.Errorf("\nA cluster-sourced definition should convert like its source manifest.\n%s: -want, +got spec:\n%s", crd.GetName(), diff)
+		}
+	}
+}
+
+func TestSchemalessVersion(t *testing.T) {
+	mixed := baseXRD + `  - name: v2
+    served: true
+    referenceable: false
+  claimNames:
+    kind: Thing
+    plural: things
+`
+	for _, crd := range deriveCRDs(t, mixed, testConfig()) {
+		if err := selfValidate(context.Background(), crd); err != nil {
+			t.Errorf("\nA definition with a schema-less version should produce a valid CRD.\nselfValidate(%s): %v", crd.GetName(), err)
+		}
+		for _, v := range crd.Spec.Versions {
+			s := v.Schema.OpenAPIV3Schema
+			for _, f := range []string{"apiVersion", "kind", "metadata", "spec", "status"} {
+				if _, ok := s.Properties[f]; !ok {
+					t.Errorf("\nEvery version should have the base properties.\n%s %s: no %s", crd.GetName(), v.Name, f)
+				}
+			}
+			if _, ok := s.Properties["spec"].Properties["compositionRef"]; !ok {
+				t.Errorf("\nEvery version should have the injected spec properties.\n%s %s: no spec.compositionRef", crd.GetName(), v.Name)
+			}
+			if _, ok := s.Properties["status"].Properties["conditions"]; !ok {
+				t.Errorf("\nEvery version should have the injected status properties.\n%s %s: no status.conditions", crd.GetName(), v.Name)
+			}
+		}
+		if _, ok := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["base"]; !ok {
+			t.Errorf("\nA version with a schema should keep its own properties.\n%s v1: no spec.base", crd.GetName())
+		}
+	}
+}
+
+func TestGetPropFields(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		props  map[string]extv1.JSONSchemaProps
+		want   []string
+	}{
+		"Empty": {
+			reason: "No properties should have no fields.",
+			want:   []string{},
+		},
+		"Sorted": {
+			reason: "Fields should be returned in sorted order, whatever the map's iteration order.",
+			props: map[string]extv1.JSONSchemaProps{
+				"zeta": {}, "alpha": {}, "mid": {}, "beta": {}, "omega": {}, "gamma": {}, "delta": {}, "kappa": {},
+			},
+			want: []string{"alpha", "beta", "delta", "gamma", "kappa", "mid", "omega", "zeta"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// Map iteration order varies between iterations, so the fields
+			// are listed a few times to catch any dependence on it.
+			for i := 0; i < 10; i++ {
+				if diff := cmp.Diff(tc.want, GetPropFields(tc.props)); diff != "" {
+					t.Fatalf("\n%s\nGetPropFields(...): -want, +got:\n%s", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestClaimNames(t *testing.T) {
+	composite := strings.Replace(baseXRD, "    plural: xthings\n", "    plural: xthings\n    singular: xthing\n    listKind: XThingList\n    shortNames: [xt]\n", 1)
+
+	cases := map[string]struct {
+		reason  string
+		claim   string
+		want    extv1.CustomResourceDefinitionNames
+		wantErr string
+	}{
+		"AllNames": {
+			reason: "Every claim name the definition declares should be carried over to the claim CRD.",
+			claim: `  claimNames:
+    kind: Thing
+    plural: things
+    singular: thing
+    listKind: ThingCollection
+    shortNames: [th, thg]
+    categories: [team]
+`,
+			want: extv1.CustomResourceDefinitionNames{
+				Kind:       "Thing",
+				Plural:     "things",
+				Singular:   "thing",
+				ListKind:   "ThingCollection",
+				ShortNames: []string{"th", "thg"},
+				Categories: []string{"team", CategoryClaim},
+			},
+		},
+		"CollidingSingular": {
+			reason: "A claim singular the composite resource has should be rejected.",
+			claim: `  claimNames:
+    kind: Thing
+    plural: things
+    singular: xthing
+`,
+			wantErr: `"xthing" conflicts with composite resource name`,
+		},
+		"CollidingListKind": {
+			reason: "A claim list kind the composite resource has should be rejected.",
+			claim: `  claimNames:
+    kind: Thing
+    plural: things
+    listKind: XThingList
+`,
+			wantErr: `"XThingList" conflicts with composite resource name`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := decodeXrd([]byte(composite + tc.claim))
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd, err := ForCompositeResourceClaim(d.CompositeResourceDefinition)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("\n%s\nForCompositeResourceClaim(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("\n%s\nForCompositeResourceClaim(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			case err != nil:
+				return
+			}
+			if diff := cmp.Diff(tc.want, crd.Spec.Names); diff != "" {
+				t.Errorf("\n%s\nForCompositeResourceClaim(...): -want, +got names:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSetStorageVersion(t *testing.T) {
+	version := func(name string, served, storage bool) extv1.CustomResourceDefinitionVersion {
+		return extv1.CustomResourceDefinitionVersion{Name: name, Served: served, Storage: storage}
+	}
+
+	cases := map[string]struct {
+		reason   string
+		versions []extv1.CustomResourceDefinitionVersion
+		name     string
+		want     []string
+		wantErr  bool
+	}{
+		"Referenceable": {
+			reason:   "The version derived from the referenceable one should be stored.",
+			versions: []extv1.CustomResourceDefinitionVersion{version("v1alpha1", true, false), version("v1beta1", true, true)},
+			want:     []string{"v1beta1"},
+		},
+		"SeveralReferenceable": {
+			reason:   "Several referenceable versions should be rejected.",
+			versions: []extv1.CustomResourceDefinitionVersion{version("v1alpha1", true, true), version("v1beta1", true, true)},
+			wantErr:  true,
+		},
+		"NoneReferenceable": {
+			reason:   "The latest served version should be stored if none is referenceable.",
+			versions: []extv1.CustomResourceDefinitionVersion{version("v1alpha1", true, false), version("v1beta1", true, false), version("v1", false, false)},
+			want:     []string{"v1beta1"},
+		},
+		"Named": {
+			reason:   "Only the named version should be stored.",
+			versions: []extv1.CustomResourceDefinitionVersion{version("v1alpha1", true, false), version("v1beta1", true, true)},
+			name:     "v1alpha1",
+			want:     []string{"v1alpha1"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := &extv1.CustomResourceDefinition{Spec: extv1.CustomResourceDefinitionSpec{Versions: tc.versions}}
+			err := setStorageVersion(crd, tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nsetStorageVersion(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var got []string
+			for _, v := range crd.Spec.Versions {
+				if v.Storage {
+					got = append(got, v.Name)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nsetStorageVersion(...): -want, +got storage versions:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
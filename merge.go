@@ -0,0 +1,204 @@
+package main
+
+import (
+	"os"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Merge strategies.
+const (
+	MergeByGroupKind = "group-kind"
+)
+
+const (
+	errFmtUnknownMergeBy = "unknown merge strategy %q"
+	errFmtMergeDefs      = "cannot merge definitions of %s"
+)
+
+// mergeDefinitions groups the definitions in the files at the supplied paths
+// by group and kind and deep merges each group, with later documents taking
+// precedence. Documents are only checked once merged, so a group may hold
+// partial definitions. It returns the paths holding the first definition of
+// a group, in order of appearance, and the merged definitions each returned
+// path stands for.
+func mergeDefinitions(paths []string) ([]string, map[string][]*definition, error) {
+	type group struct {
+		path    string
+		index   int
+		sources int
+		doc     []byte
+		merged  map[string]interface{}
+	}
+	var order []schema.GroupKind
+	groups := map[schema.GroupKind]*group{}
+
+	for _, p := range paths {
+		docs, err := loadDocuments(p)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, errFmtLoadXrd, p)
+		}
+		var others []string
+		found := false
+		for i, d := range docs {
+			t, err := typeOf(d)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, errFmtLoadXrd, p)
+			}
+			if t.Kind != v1.CompositeResourceDefinitionKind {
+				others = append(others, describeKind(t))
+				continue
+			}
+			found = true
+			doc := map[string]interface{}{}
+			if err := yaml.Unmarshal(d, &doc); err != nil {
+				return nil, nil, errors.Wrapf(err, errFmtLoadXrd, p)
+			}
+			gk := schema.GroupKind{Group: nestedString(doc, "spec", "group"), Kind: nestedString(doc, "spec", "names", "kind")}
+			g, ok := groups[gk]
+			if !ok {
+				order = append(order, gk)
+				groups[gk] = &group{path: p, index: i, sources: 1, doc: d, merged: doc}
+				continue
+			}
+			g.sources++
+			g.merged = mergeValues(g.merged, doc).(map[string]interface{})
+		}
+		if !found {
+			return nil, nil, errors.Wrapf(noDefinitionsError(others), errFmtLoadXrd, p)
+		}
+	}
+
+	var out []string
+	merged := map[string][]*definition{}
+	for _, gk := range order {
+		g := groups[gk]
+		doc := g.doc
+		if g.sources > 1 {
+			y, err := yaml.Marshal(g.merged)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, errFmtMergeDefs, gk)
+			}
+			doc = y
+		}
+		// Merged documents are decoded like any other, so they are checked
+		// and read in full.
+		xrd, err := decodeXrd(doc)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, errFmtMergeDefs, gk)
+		}
+		xrd.path, xrd.index = g.path, g.index
+		if _, ok := merged[g.path]; !ok {
+			out = append(out, g.path)
+		}
+		merged[g.path] = append(merged[g.path], xrd)
+	}
+	return out, merged, nil
+}
+
+// mergeValues deep merges overlay into base. Objects are merged key by key and
+// lists of objects with a name are merged by name. The required lists of
+// schemas are unioned. Any other overlay value replaces the base value.
+func mergeValues(base, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case map[string]interface{}:
+		b, ok := base.(map[string]interface{})
+		if !ok {
+			return o
+		}
+		for k, v := range o {
+			if k == "required" {
+				b[k] = unionLists(b[k], v)
+				continue
+			}
+			b[k] = mergeValues(b[k], v)
+		}
+		return b
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok || !namedList(b) || !namedList(o) {
+			return o
+		}
+		for _, ov := range o {
+			name := ov.(map[string]interface{})["name"]
+			found := false
+			for i, bv := range b {
+				if bv.(map[string]interface{})["name"] == name {
+					b[i] = mergeValues(bv, ov)
+					found = true
+					break
+				}
+			}
+			if !found {
+				b = append(b, ov)
+			}
+		}
+		return b
+	}
+	return overlay
+}
+
+// namedList returns true if every element of l is an object with a name.
+func namedList(l []interface{}) bool {
+	for _, v := range l {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := m["name"]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// unionLists returns the elements of base followed by those of overlay that
+// are not already present.
+func unionLists(base, overlay interface{}) interface{} {
+	b, ok := base.([]interface{})
+	o, ook := overlay.([]interface{})
+	if !ok || !ook {
+		return overlay
+	}
+	for _, ov := range o {
+		found := false
+		for _, bv := range b {
+			if bv == ov {
+				found = true
+				break
+			}
+		}
+		if !found {
+			b = append(b, ov)
+		}
+	}
+	return b
+}
+
+// loadDocuments reads the documents of the YAML stream in the file at path.
+func loadDocuments(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readDocuments(f)
+}
+
+// nestedString returns the string at the supplied field path of doc, or an
+// empty string if there is none.
+func nestedString(doc map[string]interface{}, fields ...string) string {
+	var v interface{} = doc
+	for _, f := range fields {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[f]
+	}
+	s, _ := v.(string)
+	return s
+}
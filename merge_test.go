@@ -0,0 +1,93 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeDefinitions(t *testing.T) {
+	override := strings.Replace(overlayXRD, `              overlay:
+                type: string
+`, `              base:
+                type: integer
+            required: [base]
+`, 1)
+	other := strings.NewReplacer("XThing", "XOther", "xthings", "xothers").Replace(baseXRD)
+
+	cases := map[string]struct {
+		reason    string
+		files     [][2]string
+		wantPaths []string
+		want      map[string]map[string]string
+		required  []string
+	}{
+		"BaseAndOverlay": {
+			reason:    "Partial definitions of the same group and kind should be merged into one CRD.",
+			files:     [][2]string{{"a/xrd.yaml", baseXRD}, {"b/xrd.yaml", overlayXRD}},
+			wantPaths: []string{"a/xrd.yaml"},
+			want:      map[string]map[string]string{"xthings.example.org": {"base": "string", "overlay": "string"}},
+		},
+		"LaterOverrides": {
+			reason:    "Fields of later definitions should take precedence, and required fields should be unioned.",
+			files:     [][2]string{{"a/xrd.yaml", baseXRD}, {"b/xrd.yaml", override}},
+			wantPaths: []string{"a/xrd.yaml"},
+			want:      map[string]map[string]string{"xthings.example.org": {"base": "integer"}},
+			required:  []string{"base"},
+		},
+		"DistinctKinds": {
+			reason:    "Definitions of different kinds should not be merged.",
+			files:     [][2]string{{"a/xrd.yaml", baseXRD}, {"b/xrd.yaml", other}},
+			wantPaths: []string{"a/xrd.yaml", "b/xrd.yaml"},
+			want: map[string]map[string]string{
+				"xthings.example.org": {"base": "string"},
+				"xothers.example.org": {"base": "string"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths, merged, err := mergeDefinitions(writeFiles(t, dir, tc.files...))
+			if err != nil {
+				t.Fatalf("\n%s\nmergeDefinitions(...): %v", tc.reason, err)
+			}
+			var gotPaths []string
+			for _, p := range paths {
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotPaths = append(gotPaths, filepath.ToSlash(rel))
+			}
+			if diff := cmp.Diff(tc.wantPaths, gotPaths); diff != "" {
+				t.Errorf("\n%s\nmergeDefinitions(...): -want, +got paths:\n%s", tc.reason, diff)
+			}
+
+			got := map[string]map[string]string{}
+			for _, p := range paths {
+				for _, xrd := range merged[p] {
+					crd, err := compositeGenerator()(xrd)
+					if err != nil {
+						t.Fatalf("\n%s\nForCompositeResource(...): %v", tc.reason, err)
+					}
+					spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+					got[crd.GetName()] = map[string]string{}
+					for _, k := range []string{"base", "overlay"} {
+						if s, ok := spec.Properties[k]; ok {
+							got[crd.GetName()][k] = s.Type
+						}
+					}
+					if diff := cmp.Diff(tc.required, spec.Required); diff != "" {
+						t.Errorf("\n%s\n%s: -want, +got required spec fields:\n%s", tc.reason, crd.GetName(), diff)
+					}
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nmergeDefinitions(...): -want, +got field types:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
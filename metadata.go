@@ -0,0 +1,32 @@
+package main
+
+import (
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+)
+
+// readSpecMetadata merges the labels and annotations of the spec.metadata of
+// the supplied XRD document into the metadata of xrd. Crossplane 1.14 and
+// later add them to the CRDs it derives from an XRD, as this tool does with
+// the XRD's own labels and annotations. Those of spec.metadata take
+// precedence.
+func readSpecMetadata(doc []byte, xrd *v1.CompositeResourceDefinition) error {
+	m := struct {
+		Spec struct {
+			Metadata struct {
+				Labels      map[string]string `json:"labels"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		} `json:"spec"`
+	}{}
+	if err := yaml.Unmarshal(doc, &m); err != nil {
+		return err
+	}
+	if l := m.Spec.Metadata.Labels; len(l) > 0 {
+		xrd.SetLabels(mergeStrings(xrd.GetLabels(), l))
+	}
+	if a := m.Spec.Metadata.Annotations; len(a) > 0 {
+		xrd.SetAnnotations(mergeStrings(xrd.GetAnnotations(), a))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// defaultNameTemplate names output files after the group and plural of their
+// CRD.
+const defaultNameTemplate = "{{.Group}}_{{.Plural}}.{{.Ext}}"
+
+const (
+	errFmtParseNameTemplate = "cannot parse name template %q"
+	errFmtNameOutside       = "output name %q is outside the output directory"
+)
+
+// outputName holds the fields available to a name template.
+type outputName struct {
+	// Group, Plural, Kind and Scope are those of the generated CRD.
+	Group  string
+	Plural string
+	Kind   string
+	Scope  string
+	// Ext is the extension of the output format, without a leading dot.
+	Ext string
+	// Dir is the directory of the definition relative to the input
+	// directory.
+	Dir string
+}
+
+// parseNameTemplate parses a template for the names of output files. It
+// executes the template once, so that references to unknown fields fail
+// before any definition is converted.
+func parseNameTemplate(s string) (*template.Template, error) {
+	t, err := template.New("name").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtParseNameTemplate, s)
+	}
+	if err := t.Execute(&bytes.Buffer{}, outputName{}); err != nil {
+		return nil, errors.Wrapf(err, errFmtParseNameTemplate, s)
+	}
+	return t, nil
+}
+
+// outputPath returns the path, beneath the output folder, of the file to write
+// the supplied CRD, generated from the definition at path m, to.
+func outputPath(outputFolder, m string, crd *extv1.CustomResourceDefinition, cfg *config) (string, error) {
+	dir, err := filepath.Rel(cfg.input, filepath.Dir(m))
+	if err != nil {
+		dir = ""
+	}
+	n := outputName{
+		Group:  crd.Spec.Group,
+		Plural: crd.Spec.Names.Plural,
+		Kind:   crd.Spec.Names.Kind,
+		Scope:  string(crd.Spec.Scope),
+		Ext:    cfg.format,
+		Dir:    filepath.ToSlash(dir),
+	}
+	b := &bytes.Buffer{}
+	if err := cfg.nameTemplate.Execute(b, n); err != nil {
+		return "", err
+	}
+	name := filepath.Clean(filepath.FromSlash(b.String()))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf(errFmtNameOutside, b.String())
+	}
+	return filepath.Join(outputFolder, name), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// Claim naming conventions.
+const (
+	// ClaimNamesStripX derives claim names by removing the X prefix that
+	// composite resource names conventionally carry, e.g. XDatabase becomes
+	// Database.
+	ClaimNamesStripX = "strip-x"
+
+	// ClaimNamesStripComposite derives claim names by removing a Composite
+	// prefix, e.g. CompositeDatabase becomes Database.
+	ClaimNamesStripComposite = "strip-composite"
+
+	// ClaimNamesClaimSuffix derives claim names by appending a Claim suffix,
+	// e.g. Database becomes DatabaseClaim.
+	ClaimNamesClaimSuffix = "claim-suffix"
+)
+
+const (
+	errFmtUnknownClaimConvention = "unknown claim naming convention %q"
+	errFmtConventionNotApplies   = "claim naming convention %q does not apply to kind %q"
+)
+
+// DeriveClaimNames derives the names of a composite resource claim from those
+// of its composite resource by applying the supplied naming convention. It
+// returns an error if the derived names are invalid or collide with the
+// composite resource's names.
+func DeriveClaimNames(composite extv1.CustomResourceDefinitionNames, convention string) (extv1.CustomResourceDefinitionNames, error) {
+	var transform func(s string) string
+	switch convention {
+	case ClaimNamesStripX:
+		transform = func(s string) string { return trimPrefixFold(s, "x") }
+	case ClaimNamesStripComposite:
+		transform = func(s string) string { return trimPrefixFold(s, "composite") }
+	case ClaimNamesClaimSuffix:
+		transform = func(s string) string { return s + "Claim" }
+	default:
+		return extv1.CustomResourceDefinitionNames{}, errors.Errorf(errFmtUnknownClaimConvention, convention)
+	}
+
+	kind := transform(composite.Kind)
+	if kind == "" || kind == composite.Kind {
+		return extv1.CustomResourceDefinitionNames{}, errors.Errorf(errFmtConventionNotApplies, convention, composite.Kind)
+	}
+
+	claim := extv1.CustomResourceDefinitionNames{
+		Kind:   kind,
+		Plural: strings.ToLower(kind) + "s",
+	}
+	if composite.Singular != "" {
+		claim.Singular = strings.ToLower(kind)
+	}
+	if composite.ListKind != "" {
+		claim.ListKind = kind + "List"
+	}
+
+	if err := validateClaimNamesFor(composite, claim); err != nil {
+		return extv1.CustomResourceDefinitionNames{}, err
+	}
+	return claim, nil
+}
+
+// trimPrefixFold removes the supplied prefix from s, ignoring case.
+func trimPrefixFold(s, prefix string) string {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):]
+	}
+	return s
+}
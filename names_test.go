@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestDeriveClaimNames(t *testing.T) {
+	cases := map[string]struct {
+		reason     string
+		composite  extv1.CustomResourceDefinitionNames
+		convention string
+		want       extv1.CustomResourceDefinitionNames
+		wantErr    string
+	}{
+		"StripX": {
+			reason:     "The X prefix should be removed.",
+			composite:  extv1.CustomResourceDefinitionNames{Kind: "XDatabase", Plural: "xdatabases"},
+			convention: ClaimNamesStripX,
+			want:       extv1.CustomResourceDefinitionNames{Kind: "Database", Plural: "databases"},
+		},
+		"StripComposite": {
+			reason:     "The Composite prefix should be removed, and singular and list kind derived if the composite has them.",
+			composite:  extv1.CustomResourceDefinitionNames{Kind: "CompositeDatabase", Plural: "compositedatabases", Singular: "compositedatabase", ListKind: "CompositeDatabaseList"},
+			convention: ClaimNamesStripComposite,
+			want:       extv1.CustomResourceDefinitionNames{Kind: "Database", Plural: "databases", Singular: "database", ListKind: "DatabaseList"},
+		},
+		"ClaimSuffix": {
+			reason:     "A Claim suffix should be appended.",
+			composite:  extv1.CustomResourceDefinitionNames{Kind: "Database", Plural: "databases"},
+			convention: ClaimNamesClaimSuffix,
+			want:       extv1.CustomResourceDefinitionNames{Kind: "DatabaseClaim", Plural: "databaseclaims"},
+		},
+		"NotApplicable": {
+			reason:     "A convention that does not change the kind should be rejected.",
+			composite:  extv1.CustomResourceDefinitionNames{Kind: "Database", Plural: "databases"},
+			convention: ClaimNamesStripX,
+			wantErr:    `claim naming convention "strip-x" does not apply to kind "Database"`,
+		},
+		"Collision": {
+			reason:     "Derived names that collide with the composite resource's names should be rejected.",
+			composite:  extv1.CustomResourceDefinitionNames{Kind: "Database", Plural: "databaseclaims"},
+			convention: ClaimNamesClaimSuffix,
+			wantErr:    `"databaseclaims" conflicts with composite resource name`,
+		},
+		"UnknownConvention": {
+			reason:     "An unknown convention should be rejected.",
+			composite:  extv1.CustomResourceDefinitionNames{Kind: "XDatabase", Plural: "xdatabases"},
+			convention: "strip-y",
+			wantErr:    `unknown claim naming convention "strip-y"`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := DeriveClaimNames(tc.composite, tc.convention)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("\n%s\nDeriveClaimNames(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("\n%s\nDeriveClaimNames(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nDeriveClaimNames(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// options configures how a CRD is derived from an XRD.
+type options struct {
+	categories    []string
+	shortNames    []string
+	annotations   map[string]string
+	labels        map[string]string
+	minimalStatus bool
+
+	omitCompositionUpdatePolicy bool
+
+	baseSchema     *extv1.JSONSchemaProps
+	maxNameLength  int64
+	printerColumns []extv1.CustomResourceColumnDefinition
+	groupSuffix    string
+
+	claimNamesConvention string
+	requireUserFields    bool
+	scale                *extv1.CustomResourceSubresourceScale
+	storageVersion       string
+	exclusiveRefs        bool
+	enumMapping          map[string]string
+	deletePolicy         string
+	celRules             bool
+	versions             []string
+	conversion           *extv1.CustomResourceConversion
+	serveAll             bool
+	scope                string
+}
+
+// An Option configures how a CRD is derived from an XRD.
+type Option func(*options)
+
+// WithCategories adds categories to the derived CRD in addition to the
+// built-in composite or claim category. Categories from the XRD's category
+// annotations come before them.
+func WithCategories(c ...string) Option {
+	return func(o *options) {
+		o.categories = append(o.categories, c...)
+	}
+}
+
+// WithShortNames adds short names to the derived CRD, after those the XRD
+// specifies.
+func WithShortNames(n ...string) Option {
+	return func(o *options) {
+		o.shortNames = append(o.shortNames, n...)
+	}
+}
+
+// WithAnnotations adds annotations to the derived CRD. Later options take
+// precedence over earlier ones for the same key.
+func WithAnnotations(a map[string]string) Option {
+	return func(o *options) {
+		o.annotations = mergeStrings(o.annotations, a)
+	}
+}
+
+// WithLabels adds labels to the derived CRD, taking precedence over those
+// copied from the XRD. Later options take precedence over earlier ones for the
+// same key.
+func WithLabels(l map[string]string) Option {
+	return func(o *options) {
+		o.labels = mergeStrings(o.labels, l)
+	}
+}
+
+// WithMinimalStatus injects only the conditions status field, rather than all
+// Crossplane status fields, into versions whose schema defines no status.
+func WithMinimalStatus() Option {
+	return func(o *options) {
+		o.minimalStatus = true
+	}
+}
+
+// WithoutCompositionUpdatePolicy omits the injected compositionUpdatePolicy
+// spec field, for clusters that pin the policy through admission control.
+func WithoutCompositionUpdatePolicy() Option {
+	return func(o *options) {
+		o.omitCompositionUpdatePolicy = true
+	}
+}
+
+// WithBaseSchema merges the spec and status properties of the supplied schema
+// into those of every version. Properties defined by a version take
+// precedence.
+func WithBaseSchema(s *extv1.JSONSchemaProps) Option {
+	return func(o *options) {
+		o.baseSchema = s
+	}
+}
+
+// WithMaxNameLength constrains the metadata schema of the derived CRD to names
+// of at most the supplied length.
+func WithMaxNameLength(n int64) Option {
+	return func(o *options) {
+		o.maxNameLength = n
+	}
+}
+
+// WithPrinterColumns adds the supplied printer columns to every version, after
+// those the version defines.
+func WithPrinterColumns(cols ...extv1.CustomResourceColumnDefinition) Option {
+	return func(o *options) {
+		o.printerColumns = append(o.printerColumns, cols...)
+	}
+}
+
+// WithGroupSuffix appends the supplied suffix to the API group of the derived
+// CRD, e.g. to promote distinct groups through environments.
+func WithGroupSuffix(suffix string) Option {
+	return func(o *options) {
+		o.groupSuffix = suffix
+	}
+}
+
+// WithClaimNamesConvention derives claim names using the supplied convention
+// when an XRD does not specify them. See DeriveClaimNames.
+func WithClaimNamesConvention(convention string) Option {
+	return func(o *options) {
+		o.claimNamesConvention = convention
+	}
+}
+
+// WithRequiredUserFields requires every top-level spec field declared by the
+// XRD. Fields injected for Crossplane stay optional.
+func WithRequiredUserFields() Option {
+	return func(o *options) {
+		o.requireUserFields = true
+	}
+}
+
+// WithScale enables the scale subresource on the derived CRD. XRD annotations
+// override its paths.
+func WithScale(s extv1.CustomResourceSubresourceScale) Option {
+	return func(o *options) {
+		o.scale = &s
+	}
+}
+
+// WithExclusiveCompositionRefs adds a CEL validation rule to the spec of the
+// derived CRD that forbids setting both compositionRef and
+// compositionSelector. See ExclusiveCompositionRefsRule.
+func WithExclusiveCompositionRefs() Option {
+	return func(o *options) {
+		o.exclusiveRefs = true
+	}
+}
+
+// WithEnumMapping replaces the enum values of the XRD's fields, and the
+// defaults of those fields, according to the supplied map of old to new value.
+func WithEnumMapping(m map[string]string) Option {
+	return func(o *options) {
+		o.enumMapping = m
+	}
+}
+
+// WithCompositeDeletePolicy sets the default compositeDeletePolicy of a
+// derived claim CRD.
+func WithCompositeDeletePolicy(p string) Option {
+	return func(o *options) {
+		o.deletePolicy = p
+	}
+}
+
+// WithCELRules lets the derived CRD use CEL validation rules to mirror
+// constraints Crossplane otherwise only enforces at runtime, such as an
+// enforced composition. See EnforcedCompositionRefRule.
+func WithCELRules() Option {
+	return func(o *options) {
+		o.celRules = true
+	}
+}
+
+// WithVersions derives only the named versions of the XRD. The storage
+// version is chosen among them.
+func WithVersions(names ...string) Option {
+	return func(o *options) {
+		o.versions = names
+	}
+}
+
+// WithConversion sets the conversion between the versions of the derived CRD,
+// which is otherwise None.
+func WithConversion(c extv1.CustomResourceConversion) Option {
+	return func(o *options) {
+		o.conversion = &c
+	}
+}
+
+// WithScope sets the scope of the composite resources the XRD defines, as a
+// v2 XRD declares it: Namespaced, Cluster or LegacyCluster. Composite
+// resource CRDs are cluster scoped unless it is Namespaced.
+func WithScope(s string) Option {
+	return func(o *options) {
+		o.scope = s
+	}
+}
+
+// WithServeAll serves every version of the derived CRD, whether or not the
+// XRD serves it.
+func WithServeAll() Option {
+	return func(o *options) {
+		o.serveAll = true
+	}
+}
+
+// WithStorageVersion stores the named version, overriding the referenceable
+// version of the XRD.
+func WithStorageVersion(name string) Option {
+	return func(o *options) {
+		o.storageVersion = name
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// argoCDAnnotations are the annotations --argocd stamps onto generated CRDs.
+// Large CRDs exceed the size limit of the last-applied-configuration
+// annotation used by client-side apply, so ArgoCD must apply them server-side.
+var argoCDAnnotations = map[string]string{
+	"argocd.argoproj.io/sync-options": "ServerSideApply=true",
+}
+
+// commonOptions returns the options used to derive both composite resource and
+// composite resource claim CRDs.
+func (c *config) commonOptions() []Option {
+	var opts []Option
+	if c.argoCD {
+		opts = append(opts, WithAnnotations(argoCDAnnotations))
+	}
+	if len(c.labels) > 0 {
+		opts = append(opts, WithLabels(c.labels))
+	}
+	if c.minimalStatus {
+		opts = append(opts, WithMinimalStatus())
+	}
+	if c.omitUpdatePolicy {
+		opts = append(opts, WithoutCompositionUpdatePolicy())
+	}
+	if c.baseSchema != nil {
+		opts = append(opts, WithBaseSchema(c.baseSchema))
+	}
+	if c.maxNameLength > 0 {
+		opts = append(opts, WithMaxNameLength(c.maxNameLength))
+	}
+	if len(c.columns) > 0 {
+		opts = append(opts, WithPrinterColumns(c.columns...))
+	}
+	if c.groupSuffix != "" {
+		opts = append(opts, WithGroupSuffix(c.groupSuffix))
+	}
+	if c.inferClaimNames != "" {
+		opts = append(opts, WithClaimNamesConvention(c.inferClaimNames))
+	}
+	if c.requireAll {
+		opts = append(opts, WithRequiredUserFields())
+	}
+	if c.storageVersion != "" {
+		opts = append(opts, WithStorageVersion(c.storageVersion))
+	}
+	if len(c.versions) > 0 {
+		opts = append(opts, WithVersions(c.versions...))
+	}
+	if c.serveAll {
+		opts = append(opts, WithServeAll())
+	}
+	if c.conversion != nil {
+		opts = append(opts, WithConversion(WebhookConversion(c.conversion)))
+	}
+	if c.exclusiveRefs {
+		opts = append(opts, WithExclusiveCompositionRefs())
+	}
+	if checkCELSupported(c.minKubeVersion) == nil {
+		opts = append(opts, WithCELRules())
+	}
+	if len(c.enumMapping) > 0 {
+		opts = append(opts, WithEnumMapping(c.enumMapping))
+	}
+	if c.deletePolicy != "" {
+		opts = append(opts, WithCompositeDeletePolicy(c.deletePolicy))
+	}
+	if c.scaleSpecReplicasPath != "" || c.scaleStatusReplicasPath != "" || c.scaleLabelSelectorPath != "" {
+		s := extv1.CustomResourceSubresourceScale{
+			SpecReplicasPath:   c.scaleSpecReplicasPath,
+			StatusReplicasPath: c.scaleStatusReplicasPath,
+		}
+		if c.scaleLabelSelectorPath != "" {
+			s.LabelSelectorPath = &c.scaleLabelSelectorPath
+		}
+		opts = append(opts, WithScale(s))
+	}
+	return opts
+}
+
+// compositeOptions returns the options used to derive composite resource CRDs.
+func (c *config) compositeOptions() []Option {
+	return append(c.commonOptions(),
+		WithCategories(c.compositeCategories...),
+		WithShortNames(c.compositeShortNames...),
+		WithAnnotations(c.compositeAnnotations),
+	)
+}
+
+// claimOptions returns the options used to derive composite resource claim
+// CRDs.
+func (c *config) claimOptions() []Option {
+	return append(c.commonOptions(),
+		WithCategories(c.claimCategories...),
+		WithShortNames(c.claimShortNames...),
+		WithAnnotations(c.claimAnnotations),
+	)
+}
+
+// dedupe returns s without repeated values, keeping the first occurrence of
+// each.
+func dedupe(s []string) []string {
+	seen := make(map[string]bool, len(s))
+	out := make([]string, 0, len(s))
+	for _, v := range s {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+// mergeStrings returns a new map holding the entries of each supplied map, with
+// later maps taking precedence. It returns nil if there are no entries.
+func mergeStrings(ms ...map[string]string) map[string]string {
+	var out map[string]string
+	for _, m := range ms {
+		for k, v := range m {
+			if out == nil {
+				out = map[string]string{}
+			}
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestArgoCD(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		argoCD bool
+		want   map[string]string
+	}{
+		"Preset": {
+			reason: "--argocd should annotate each CRD with the sync options ArgoCD needs.",
+			argoCD: true,
+			want:   map[string]string{"argocd.argoproj.io/sync-options": "ServerSideApply=true"},
+		},
+		"NoPreset": {
+			reason: "CRDs should not be annotated for ArgoCD unless asked.",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.argoCD = tc.argoCD
+			for _, crd := range deriveCRDs(t, claimXRD, cfg) {
+				if diff := cmp.Diff(tc.want, crd.GetAnnotations()); diff != "" {
+					t.Errorf("\n%s\n%s: -want, +got annotations:\n%s", tc.reason, crd.GetName(), diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMinimalStatus(t *testing.T) {
+	withStatus := baseXRD + `          status:
+            type: object
+            properties:
+              ready:
+                type: boolean
+`
+
+	cases := map[string]struct {
+		reason  string
+		xrd     string
+		minimal bool
+		want    []string
+	}{
+		"Default": {
+			reason: "Crossplane's status fields should be injected into a version that defines no status.",
+			xrd:    baseXRD,
+			want:   []string{"conditions", "connectionDetails"},
+		},
+		"Minimal": {
+			reason:  "Only conditions should be injected into a version that defines no status under --minimal-status.",
+			xrd:     baseXRD,
+			minimal: true,
+			want:    []string{"conditions"},
+		},
+		"MinimalWithStatus": {
+			reason:  "--minimal-status should not affect a version that defines a status.",
+			xrd:     withStatus,
+			minimal: true,
+			want:    []string{"conditions", "connectionDetails", "ready"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.minimalStatus = tc.minimal
+			crd := deriveCRDs(t, tc.xrd, cfg)[0]
+			got := sortedKeys(crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"].Properties)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nstatus fields: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestOmitCompositionUpdatePolicy(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		omit   bool
+		want   bool
+	}{
+		"Default": {
+			reason: "compositionUpdatePolicy should be injected by default.",
+			want:   true,
+		},
+		"Omitted": {
+			reason: "compositionUpdatePolicy should be absent under --omit-composition-update-policy.",
+			omit:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.omitUpdatePolicy = tc.omit
+			for _, crd := range deriveCRDs(t, claimXRD, cfg) {
+				_, got := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["compositionUpdatePolicy"]
+				if got != tc.want {
+					t.Errorf("\n%s\n%s: compositionUpdatePolicy present: %t, want %t", tc.reason, crd.GetName(), got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestScopedAnnotations(t *testing.T) {
+	cfg := testConfig()
+	cfg.compositeAnnotations = mapFlag{"example.org/composite": "true"}
+	cfg.claimAnnotations = mapFlag{"example.org/finalizers": "keep"}
+
+	want := map[string]map[string]string{
+		"xthings.example.org": {"example.org/composite": "true"},
+		"things.example.org":  {"example.org/finalizers": "keep"},
+	}
+	got := map[string]map[string]string{}
+	for _, crd := range deriveCRDs(t, claimXRD, cfg) {
+		got[crd.GetName()] = crd.GetAnnotations()
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nScope-specific annotations should land only on the intended CRD.\n-want, +got:\n%s", diff)
+	}
+}
+
+func TestBaseSchema(t *testing.T) {
+	twoVersions := baseXRD + `  - name: v2
+    served: true
+    referenceable: false
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              region:
+                type: string
+`
+	base := &extv1.JSONSchemaProps{
+		Properties: map[string]extv1.JSONSchemaProps{
+			"spec": {
+				Properties: map[string]extv1.JSONSchemaProps{
+					"base":   {Type: "integer"},
+					"shared": {Type: "string"},
+				},
+				Required: []string{"shared"},
+			},
+		},
+	}
+
+	cfg := testConfig()
+	cfg.baseSchema = base
+	want := map[string]map[string]string{
+		"v1": {"base": "string", "shared": "string"},
+		"v2": {"base": "integer", "region": "string", "shared": "string"},
+	}
+	got := map[string]map[string]string{}
+	for _, v := range deriveCRDs(t, twoVersions, cfg)[0].Spec.Versions {
+		spec := v.Schema.OpenAPIV3Schema.Properties["spec"]
+		got[v.Name] = map[string]string{}
+		for _, k := range []string{"base", "region", "shared"} {
+			if p, ok := spec.Properties[k]; ok {
+				got[v.Name][k] = p.Type
+			}
+		}
+		if diff := cmp.Diff([]string{"shared"}, spec.Required); diff != "" {
+			t.Errorf("\nBase required fields should be required in every version.\n%s: -want, +got:\n%s", v.Name, diff)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nBase fields should appear in every version, and version fields should override them.\n-want, +got field types:\n%s", diff)
+	}
+}
+
+func TestMaxNameLength(t *testing.T) {
+	cases := map[string]struct {
+		reason        string
+		maxNameLength int64
+		want          extv1.JSONSchemaProps
+	}{
+		"Unconstrained": {
+			reason: "metadata should be a bare object by default.",
+			want:   extv1.JSONSchemaProps{Type: "object"},
+		},
+		"Constrained": {
+			reason:        "--max-name-length should constrain the length of metadata names.",
+			maxNameLength: 63,
+			want: extv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]extv1.JSONSchemaProps{
+					"name":         {Type: "string", MaxLength: pointer.Int64(63)},
+					"generateName": {Type: "string", MaxLength: pointer.Int64(63)},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.maxNameLength = tc.maxNameLength
+			for _, crd := range deriveCRDs(t, claimXRD, cfg) {
+				got := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["metadata"]
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Errorf("\n%s\n%s: -want, +got metadata schema:\n%s", tc.reason, crd.GetName(), diff)
+				}
+			}
+		})
+	}
+}
+
+func TestSharedPrinterColumns(t *testing.T) {
+	twoVersions := baseXRD + `  - name: v2
+    served: true
+    referenceable: false
+    additionalPrinterColumns:
+    - name: BASE
+      type: string
+      jsonPath: .spec.other
+    schema:
+      openAPIV3Schema:
+        type: object
+`
+	cfg := testConfig()
+	cfg.columns = []extv1.CustomResourceColumnDefinition{{Name: "BASE", Type: "string", JSONPath: ".spec.base"}}
+
+	want := map[string]string{"v1": ".spec.base", "v2": ".spec.other"}
+	for _, crd := range deriveCRDs(t, twoVersions, cfg) {
+		for _, v := range crd.Spec.Versions {
+			got := ""
+			for _, c := range v.AdditionalPrinterColumns {
+				if c.Name == "BASE" {
+					got = c.JSONPath
+				}
+			}
+			if got != want[v.Name] {
+				t.Errorf("\nShared columns should appear on every version, unless the version defines a column of the same name.\n%s %s: BASE column path %q, want %q", crd.GetName(), v.Name, got, want[v.Name])
+			}
+		}
+	}
+}
+
+func TestGroupSuffix(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		suffix  string
+		want    map[string]string
+		wantErr bool
+	}{
+		"NoSuffix": {
+			reason: "CRDs should keep the group of their definition by default.",
+			want:   map[string]string{"xthings.example.org": "example.org", "things.example.org": "example.org"},
+		},
+		"Suffix": {
+			reason: "The suffix should be appended to the group and the CRD names.",
+			suffix: "-dev",
+			want:   map[string]string{"xthings.example.org-dev": "example.org-dev", "things.example.org-dev": "example.org-dev"},
+		},
+		"InvalidGroup": {
+			reason:  "A suffix that makes the group an invalid DNS subdomain should be rejected.",
+			suffix:  "_dev",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.groupSuffix = tc.suffix
+			d, err := decodeXrd([]byte(claimXRD))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for _, generator := range generatorsFor(cfg.compositeOptions(), cfg.claimOptions(), nil) {
+				crd, err := generator(d)
+				if (err != nil) != tc.wantErr {
+					t.Fatalf("\n%s\ngenerator(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+				}
+				if crd != nil {
+					got[crd.GetName()] = crd.Spec.Group
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nCRD names and groups: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRequireAllUserFields(t *testing.T) {
+	twoFields := baseXRD + `              size:
+                type: string
+`
+	cases := map[string]struct {
+		reason     string
+		requireAll bool
+		want       []string
+	}{
+		"Default": {
+			reason: "User fields should stay optional by default.",
+		},
+		"RequireAll": {
+			reason:     "Every user spec field, and none of the injected ones, should be required.",
+			requireAll: true,
+			want:       []string{"base", "size"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.requireAll = tc.requireAll
+			for _, crd := range deriveCRDs(t, twoFields, cfg) {
+				got := append([]string{}, crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Required...)
+				sort.Strings(got)
+				if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+					t.Errorf("\n%s\n%s: -want, +got required spec fields:\n%s", tc.reason, crd.GetName(), diff)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageVersion(t *testing.T) {
+	twoVersions := baseXRD + `  - name: v1beta1
+    served: true
+    referenceable: false
+    schema:
+      openAPIV3Schema:
+        type: object
+  claimNames:
+    kind: Thing
+    plural: things
+`
+	cases := map[string]struct {
+		reason         string
+		storageVersion string
+		want           []string
+		wantErr        string
+	}{
+		"Referenceable": {
+			reason: "The referenceable version should be stored by default.",
+			want:   []string{"v1"},
+		},
+		"Override": {
+			reason:         "Only the named version should be stored.",
+			storageVersion: "v1beta1",
+			want:           []string{"v1beta1"},
+		},
+		"UnknownVersion": {
+			reason:         "A version the definition does not have should be rejected.",
+			storageVersion: "v2",
+			wantErr:        `storage version "v2" is not a version of the definition`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.storageVersion = tc.storageVersion
+			d, err := decodeXrd([]byte(twoVersions))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, generator := range generatorsFor(cfg.compositeOptions(), cfg.claimOptions(), nil) {
+				crd, err := generator(d)
+				switch {
+				case tc.wantErr == "" && err != nil:
+					t.Fatalf("\n%s\ngenerator(...): %v", tc.reason, err)
+				case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+					t.Fatalf("\n%s\ngenerator(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+				case err != nil:
+					continue
+				}
+				var got []string
+				for _, v := range crd.Spec.Versions {
+					if v.Storage {
+						got = append(got, v.Name)
+					}
+				}
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Errorf("\n%s\n%s: -want, +got storage versions:\n%s", tc.reason, crd.GetName(), diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// Output modes.
+const (
+	OutputFiles  = "files"
+	OutputStdout = "stdout"
+)
+
+// messages receives warnings and other reports about a run, such as the sizes
+// of generated CRDs. It is standard error when generated CRDs are written to
+// standard output, so they can be piped.
+var messages io.Writer = os.Stdout
+
+const (
+	errCompress          = "cannot compress output"
+	errFmtDumpDefinition = "cannot dump parsed definition %q"
+	errFmtMissingDir     = "%s directory %q does not exist"
+	errFmtNotDir         = "%s path %q is not a directory"
+	errFmtCreateDir      = "cannot create %s directory %q"
+	errGzipStdout        = "--gzip cannot be used with --stdout"
+)
+
+// checkOutputOptions returns an error if the config combines output options
+// that cannot be used together.
+func checkOutputOptions(cfg *config) error {
+	if cfg.gzip && cfg.stdout {
+		return errors.New(errGzipStdout)
+	}
+	return nil
+}
+
+// gzipBytes returns the gzip compressed form of b.
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, errors.Wrap(err, errCompress)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, errCompress)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeDocument writes y to w as a YAML document of a stream.
+func writeDocument(w io.Writer, y []byte) error {
+	if _, err := io.WriteString(w, "---\n"); err != nil {
+		return err
+	}
+	_, err := w.Write(y)
+	return err
+}
+
+// checkDir returns an error if the supplied path is not an existing
+// directory. The kind of directory is used to describe it.
+func checkDir(kind, path string) error {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return errors.Errorf(errFmtMissingDir, kind, path)
+	}
+	if err != nil {
+		return errors.Wrapf(err, errFmtNotDir, kind, path)
+	}
+	if !fi.IsDir() {
+		return errors.Errorf(errFmtNotDir, kind, path)
+	}
+	return nil
+}
+
+// ensureDir creates the supplied directory, and any missing parents, unless it
+// exists. The kind of directory is used to describe it.
+func ensureDir(kind, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return errors.Wrapf(os.MkdirAll(path, 0755), errFmtCreateDir, kind, path)
+	}
+	return checkDir(kind, path)
+}
+
+// upToDate returns true if the output file exists and was modified after the
+// input file.
+func upToDate(output, input string) bool {
+	out, err := os.Stat(output)
+	if err != nil {
+		return false
+	}
+	in, err := os.Stat(input)
+	if err != nil {
+		return false
+	}
+	return out.ModTime().After(in.ModTime())
+}
+
+// unchanged returns true if the output file exists and already holds exactly
+// the supplied bytes, so that rewriting it would only change its mtime.
+func unchanged(output string, b []byte) bool {
+	existing, err := ioutil.ReadFile(output)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(existing, b)
+}
+
+// dumpIntermediate writes the definitions at the supplied paths, as parsed by
+// this tool, to the intermediate directory of the output folder. Comparing
+// them to their sources shows which fields were not understood.
+func dumpIntermediate(paths []string, outputFolder string, cfg *config) error {
+	dir := filepath.Join(outputFolder, "intermediate")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, errFmtDumpDefinition, dir)
+	}
+	for _, p := range paths {
+		xrds, err := cfg.load(p)
+		if err != nil {
+			return errors.Wrapf(err, errFmtDumpDefinition, p)
+		}
+		for _, xrd := range xrds {
+			y, err := yaml.Marshal(xrd.CompositeResourceDefinition)
+			if err != nil {
+				return errors.Wrapf(err, errFmtDumpDefinition, p)
+			}
+			name := xrd.GetName()
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+			}
+			output := filepath.Join(dir, name+".yaml")
+			if err := ioutil.WriteFile(output, y, 0644); err != nil {
+				return errors.Wrapf(err, errFmtDumpDefinition, p)
+			}
+			cfg.log.Infof("Dumped parsed %s to %s", p, output)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGzipOutput(t *testing.T) {
+	generate := func(t *testing.T, gz bool) string {
+		t.Helper()
+		dir := t.TempDir()
+		m := writeFiles(t, dir, [2]string{"a/xrd.yaml", baseXRD})[0]
+		cfg := testConfig()
+		cfg.input = dir
+		cfg.gzip = gz
+		if err := generateCrdForPaths(context.Background(), []string{m}, filepath.Join(dir, "crds"), cfg); err != nil {
+			t.Fatalf("generateCrdForPaths(...): %v", err)
+		}
+		return filepath.Join(dir, "crds")
+	}
+	plain, err := ioutil.ReadFile(filepath.Join(generate(t, false), "example.org_xthings.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason string
+		gzip   bool
+		file   string
+	}{
+		"Plain": {
+			reason: "Without --gzip the CRD should be written as is.",
+			file:   "example.org_xthings.yaml",
+		},
+		"Gzip": {
+			reason: "With --gzip the CRD should be written to a .yaml.gz file that decompresses to the plain output.",
+			gzip:   true,
+			file:   "example.org_xthings.yaml.gz",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := ioutil.ReadFile(filepath.Join(generate(t, tc.gzip), tc.file))
+			if err != nil {
+				t.Fatalf("\n%s\ngenerateCrdForPaths(...): %v", tc.reason, err)
+			}
+			if tc.gzip {
+				zr, err := gzip.NewReader(bytes.NewReader(b))
+				if err != nil {
+					t.Fatalf("\n%s\ngenerateCrdForPaths(...): %v", tc.reason, err)
+				}
+				if b, err = io.ReadAll(zr); err != nil {
+					t.Fatalf("\n%s\ngenerateCrdForPaths(...): %v", tc.reason, err)
+				}
+			}
+			if diff := cmp.Diff(string(plain), string(b)); diff != "" {
+				t.Errorf("\n%s\ngenerateCrdForPaths(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckOutputOptions(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		cfg     config
+		wantErr string
+	}{
+		"GzipFiles": {
+			reason: "Compressed CRDs should be writable to files.",
+			cfg:    config{gzip: true},
+		},
+		"Stdout": {
+			reason: "Plain CRDs should be writable to standard output.",
+			cfg:    config{stdout: true},
+		},
+		"GzipStdout": {
+			reason:  "Compressed CRDs cannot be printed to standard output.",
+			cfg:     config{gzip: true, stdout: true},
+			wantErr: errGzipStdout,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkOutputOptions(&tc.cfg)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("\n%s\ncheckOutputOptions(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("\n%s\ncheckOutputOptions(...): got error %v, want %q", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDumpIntermediate(t *testing.T) {
+	unknown := strings.Replace(claimXRD, "spec:\n  group:", "spec:\n  unknownField: dropped\n  group:", 1)
+
+	cases := map[string]struct {
+		reason string
+		xrd    string
+	}{
+		"Known": {
+			reason: "The dumped definition should round-trip every known field.",
+			xrd:    claimXRD,
+		},
+		"Unknown": {
+			reason: "The dumped definition should omit fields that were not understood.",
+			xrd:    unknown,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			p := writeFiles(t, dir, [2]string{"a/xrd.yaml", tc.xrd})[0]
+			if err := dumpIntermediate([]string{p}, dir, testConfig()); err != nil {
+				t.Fatalf("\n%s\ndumpIntermediate(...): %v", tc.reason, err)
+			}
+			y, err := ioutil.ReadFile(filepath.Join(dir, "intermediate", "xthings.example.org.yaml"))
+			if err != nil {
+				t.Fatalf("\n%s\ndumpIntermediate(...): %v", tc.reason, err)
+			}
+			if strings.Contains(string(y), "unknownField") {
+				t.Errorf("\n%s\ndumpIntermediate(...): dump holds an unknown field:\n%s", tc.reason, y)
+			}
+
+			want, err := decodeXrd([]byte(tc.xrd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decodeXrd(y)
+			if err != nil {
+				t.Fatalf("\n%s\ndumpIntermediate(...): dump cannot be decoded: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(want.CompositeResourceDefinition, got.CompositeResourceDefinition); diff != "" {
+				t.Errorf("\n%s\ndumpIntermediate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckDir(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFiles(t, dir, [2]string{"xrd.yaml", baseXRD})[0]
+
+	cases := map[string]struct {
+		reason    string
+		path      string
+		wantCheck string
+		wantMade  bool
+	}{
+		"Directory": {
+			reason: "An existing directory should be accepted.",
+			path:   dir,
+		},
+		"Missing": {
+			reason:    "A missing directory should be reported, and created if it is an output directory.",
+			path:      filepath.Join(dir, "missing", "crds"),
+			wantCheck: `input directory "` + filepath.Join(dir, "missing", "crds") + `" does not exist`,
+			wantMade:  true,
+		},
+		"File": {
+			reason:    "A file should be rejected as a directory.",
+			path:      file,
+			wantCheck: `input path "` + file + `" is not a directory`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			if err := checkDir("input", tc.path); err != nil {
+				got = err.Error()
+			}
+			if got != tc.wantCheck {
+				t.Errorf("\n%s\ncheckDir(...): got error %q, want %q", tc.reason, got, tc.wantCheck)
+			}
+
+			err := ensureDir("output", tc.path)
+			if wantErr := tc.wantCheck != "" && !tc.wantMade; (err != nil) != wantErr {
+				t.Errorf("\n%s\nensureDir(...): got error %v, want error %t", tc.reason, err, wantErr)
+			}
+			if tc.wantMade {
+				if err := checkDir("output", tc.path); err != nil {
+					t.Errorf("\n%s\nensureDir(...): directory not created: %v", tc.reason, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	errReadPatches     = "cannot read patch file"
+	errParsePatches    = "cannot parse patch file"
+	errFmtDecodePatch  = "cannot decode patch for %q"
+	errFmtApplyPatch   = "cannot apply patch to %q"
+	errMarshalPatchCRD = "cannot marshal CRD for patching"
+	errUnmarshalPatch  = "cannot unmarshal patched CRD"
+)
+
+// patchSet holds RFC 6902 JSON patches keyed by the name of the CRD they apply
+// to, and records which of them were applied.
+type patchSet struct {
+	patches map[string]jsonpatch.Patch
+	applied map[string]bool
+}
+
+// loadPatches reads a YAML or JSON file mapping CRD names to lists of JSON
+// patch operations.
+func loadPatches(path string) (*patchSet, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadPatches)
+	}
+	raw := map[string]json.RawMessage{}
+	if err := yaml.Unmarshal(y, &raw); err != nil {
+		return nil, errors.Wrap(err, errParsePatches)
+	}
+
+	ps := &patchSet{patches: map[string]jsonpatch.Patch{}, applied: map[string]bool{}}
+	for name, r := range raw {
+		p, err := jsonpatch.DecodePatch(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtDecodePatch, name)
+		}
+		ps.patches[name] = p
+	}
+	return ps, nil
+}
+
+// apply applies the patch for the supplied CRD, if any, in place.
+func (ps *patchSet) apply(crd *extv1.CustomResourceDefinition) error {
+	p, ok := ps.patches[crd.GetName()]
+	if !ok {
+		return nil
+	}
+
+	j, err := json.Marshal(crd)
+	if err != nil {
+		return errors.Wrap(err, errMarshalPatchCRD)
+	}
+	j, err = p.Apply(j)
+	if err != nil {
+		return errors.Wrapf(err, errFmtApplyPatch, crd.GetName())
+	}
+	patched := &extv1.CustomResourceDefinition{}
+	if err := json.Unmarshal(j, patched); err != nil {
+		return errors.Wrap(err, errUnmarshalPatch)
+	}
+	*crd = *patched
+	ps.applied[crd.GetName()] = true
+	return nil
+}
+
+// unapplied returns the sorted names of patch targets that did not match any
+// generated CRD.
+func (ps *patchSet) unapplied() []string {
+	var names []string
+	for name := range ps.patches {
+		if !ps.applied[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
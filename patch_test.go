@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPatchSet(t *testing.T) {
+	const patches = `xthings.example.org:
+- op: add
+  path: /metadata/labels
+  value:
+    team: platform
+missing.example.org:
+- op: add
+  path: /metadata/labels
+  value: {}
+`
+
+	cases := map[string]struct {
+		reason        string
+		crd           string
+		patches       string
+		wantLabels    map[string]string
+		wantUnapplied []string
+		wantErr       string
+	}{
+		"AddLabel": {
+			reason:        "A patch keyed by the CRD's name should be applied to it, and other targets reported as unapplied.",
+			crd:           "xthings.example.org",
+			patches:       patches,
+			wantLabels:    map[string]string{"team": "platform"},
+			wantUnapplied: []string{"missing.example.org"},
+		},
+		"NoPatch": {
+			reason:        "A CRD no patch is keyed by should be left alone.",
+			crd:           "others.example.org",
+			patches:       patches,
+			wantUnapplied: []string{"missing.example.org", "xthings.example.org"},
+		},
+		"Failing": {
+			reason:  "A patch that cannot be applied should be reported.",
+			crd:     "xthings.example.org",
+			patches: "xthings.example.org:\n- op: replace\n  path: /metadata/nope/deeper\n  value: x\n",
+			wantErr: `cannot apply patch to "xthings.example.org"`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ps, err := loadPatches(writeFiles(t, t.TempDir(), [2]string{"patches.yaml", tc.patches})[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd := &extv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: tc.crd}}
+			err = ps.apply(crd)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("\n%s\napply(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("\n%s\napply(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.wantLabels, crd.GetLabels()); diff != "" {
+				t.Errorf("\n%s\napply(...): -want, +got labels:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.wantUnapplied, ps.unapplied()); diff != "" {
+				t.Errorf("\n%s\nunapplied(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
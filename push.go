@@ -0,0 +1,127 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+)
+
+// packageStreamFile is the file of a Crossplane package image that holds its
+// YAML stream.
+const packageStreamFile = "package.yaml"
+
+const (
+	errFmtParseReference = "cannot parse package reference %q"
+	errBuildPackage      = "cannot build package"
+	errFmtPushPackage    = "cannot push package to %q"
+)
+
+// packageMeta is the Configuration metadata that starts a package stream.
+const packageMeta = `apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: %s
+`
+
+// buildPackage builds a Crossplane Configuration package image holding the
+// definitions at the supplied paths and any compositions under
+// compositionsDir. A path that stands for merged definitions contributes
+// those instead of its own documents. Generated CRDs are not included;
+// Crossplane derives them from the definitions and rejects CRDs in
+// Configuration packages.
+func buildPackage(ctx context.Context, pkgName string, paths []string, merged map[string][]*definition, compositionsDir string) (v1.Image, error) {
+	stream := &bytes.Buffer{}
+	fmt.Fprintf(stream, packageMeta, pkgName)
+	for _, p := range paths {
+		if xrds, ok := merged[p]; ok {
+			for _, xrd := range xrds {
+				writeDocuments(stream, [][]byte{xrd.doc})
+			}
+			continue
+		}
+		y, err := ioutil.ReadFile(filepath.Clean(p))
+		if err != nil {
+			return nil, errors.Wrap(err, errBuildPackage)
+		}
+		docs, err := readDocuments(bytes.NewReader(y))
+		if err != nil {
+			return nil, errors.Wrap(err, errBuildPackage)
+		}
+		writeDocuments(stream, docs)
+	}
+	if compositionsDir != "" {
+		docs, err := findCompositions(ctx, compositionsDir)
+		if err != nil {
+			return nil, errors.Wrap(err, errBuildPackage)
+		}
+		writeDocuments(stream, docs)
+	}
+
+	tarBuf := &bytes.Buffer{}
+	tw := tar.NewWriter(tarBuf)
+	if err := tw.WriteHeader(&tar.Header{Name: packageStreamFile, Mode: 0o644, Size: int64(stream.Len())}); err != nil {
+		return nil, errors.Wrap(err, errBuildPackage)
+	}
+	if _, err := io.Copy(tw, stream); err != nil {
+		return nil, errors.Wrap(err, errBuildPackage)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, errBuildPackage)
+	}
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(tarBuf.Bytes())), nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errBuildPackage)
+	}
+	return mutate.AppendLayers(empty.Image, layer)
+}
+
+// pushPackage builds a Configuration package from the definitions at the
+// supplied paths and pushes it to an oci:// reference, authenticating with the
+// credentials of the local Docker configuration.
+func pushPackage(ctx context.Context, paths []string, cfg *config) error {
+	target := strings.TrimPrefix(cfg.push, "oci://")
+	ref, err := name.ParseReference(target)
+	if err != nil {
+		return errors.Wrapf(err, errFmtParseReference, cfg.push)
+	}
+
+	pkgName := cfg.packageName
+	if pkgName == "" {
+		pkgName = filepath.Base(ref.Context().RepositoryStr())
+	}
+	img, err := buildPackage(ctx, pkgName, paths, cfg.merged, cfg.compositionsDir)
+	if err != nil {
+		return err
+	}
+
+	if err := remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithContext(ctx)); err != nil {
+		return errors.Wrapf(err, errFmtPushPackage, target)
+	}
+	fmt.Fprintf(messages, "Pushed package %s\n", ref)
+	return nil
+}
+
+func writeDocuments(w *bytes.Buffer, docs [][]byte) {
+	for _, d := range docs {
+		w.WriteString("---\n")
+		w.Write(bytes.TrimSpace(d))
+		w.WriteString("\n")
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const (
+	baseXRD = `apiVersion: apiextensions.crossplane.io/v1
+kind: CompositeResourceDefinition
+metadata:
+  name: xthings.example.org
+spec:
+  group: example.org
+  names:
+    kind: XThing
+    plural: xthings
+  versions:
+  - name: v1
+    served: true
+    referenceable: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              base:
+                type: string
+`
+	overlayXRD = `apiVersion: apiextensions.crossplane.io/v1
+kind: CompositeResourceDefinition
+metadata:
+  name: xthings.example.org
+spec:
+  group: example.org
+  names:
+    kind: XThing
+  versions:
+  - name: v1
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              overlay:
+                type: string
+`
+)
+
+// writeFiles writes the supplied files, keyed by slash separated path, beneath
+// dir and returns their paths in the order supplied.
+func writeFiles(t *testing.T, dir string, files ...[2]string) []string {
+	t.Helper()
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f[0]))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(f[1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// pulledStream returns the package stream of the image at the supplied
+// reference.
+func pulledStream(t *testing.T, ref string) string {
+	t.Helper()
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := remote.Image(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := layers[0].Uncompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Name == packageStreamFile {
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(b)
+		}
+	}
+}
+
+func TestPushPackage(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason      string
+		mergeBy     string
+		want        []string
+		definitions int
+	}{
+		"Unmerged": {
+			reason:      "Each definition file should be packaged as is.",
+			want:        []string{"kind: Configuration", "name: things", "base:", "overlay:"},
+			definitions: 2,
+		},
+		"Merged": {
+			reason:      "Merged definitions should be packaged instead of the files they were merged from.",
+			mergeBy:     MergeByGroupKind,
+			want:        []string{"kind: Configuration", "name: things", "base:", "overlay:"},
+			definitions: 1,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			paths := writeFiles(t, t.TempDir(), [2]string{"a/xrd.yaml", baseXRD}, [2]string{"b/xrd.yaml", overlayXRD})
+			cfg := &config{push: "oci://" + u.Host + "/things:" + strings.ToLower(name), log: NewLogger(ioutil.Discard, false)}
+			if tc.mergeBy != "" {
+				var err error
+				paths, cfg.merged, err = mergeDefinitions(paths)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := pushPackage(context.Background(), paths, cfg); err != nil {
+				t.Fatalf("\n%s\npushPackage(...): %v", tc.reason, err)
+			}
+			got := pulledStream(t, strings.TrimPrefix(cfg.push, "oci://"))
+			for _, w := range tc.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("\n%s\npushPackage(...): package stream lacks %q:\n%s", tc.reason, w, got)
+				}
+			}
+			if n := strings.Count(got, "kind: CompositeResourceDefinition"); n != tc.definitions {
+				t.Errorf("\n%s\npushPackage(...): package holds %d definitions, want %d", tc.reason, n, tc.definitions)
+			}
+		})
+	}
+}
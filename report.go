@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReportVersion is the version of the run report format. It changes whenever a
+// field is removed or changes meaning.
+const ReportVersion = "xrdconvert.report/v1"
+
+// File statuses.
+const (
+	FileStatusConverted = "converted"
+	FileStatusUpToDate  = "up-to-date"
+	FileStatusFailed    = "failed"
+	FileStatusSkipped   = "skipped"
+)
+
+const (
+	errWriteReport = "cannot write report"
+)
+
+// runReport summarizes a conversion run.
+type runReport struct {
+	Version    string        `json:"version"`
+	Started    time.Time     `json:"started"`
+	DurationMs int64         `json:"durationMs"`
+	Inputs     []string      `json:"inputs"`
+	Outputs    []string      `json:"outputs"`
+	Files      []*fileReport `json:"files"`
+	Warnings   []string      `json:"warnings"`
+	Errors     []string      `json:"errors"`
+	Counts     reportCounts  `json:"counts"`
+}
+
+// reportCounts tallies the outcome of a conversion run.
+type reportCounts struct {
+	Inputs    int `json:"inputs"`
+	Converted int `json:"converted"`
+	UpToDate  int `json:"upToDate"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Warnings  int `json:"warnings"`
+}
+
+// fileReport is the outcome of generating one CRD from one input file.
+type fileReport struct {
+	Input      string   `json:"input"`
+	CRD        string   `json:"crd,omitempty"`
+	Output     string   `json:"output,omitempty"`
+	Status     string   `json:"status"`
+	Warnings   []string `json:"warnings,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	DurationMs int64    `json:"durationMs"`
+}
+
+// warn prints a warning about the file and records it.
+func (f *fileReport) warn(format string, args ...interface{}) {
+	w := fmt.Sprintf(format, args...)
+	fmt.Fprintf(messages, "Warning: %s\n", w)
+	f.Warnings = append(f.Warnings, w)
+}
+
+func newRunReport() *runReport {
+	return &runReport{
+		Version:  ReportVersion,
+		Started:  time.Now(),
+		Inputs:   []string{},
+		Outputs:  []string{},
+		Files:    []*fileReport{},
+		Warnings: []string{},
+		Errors:   []string{},
+	}
+}
+
+// addInputs records discovered input files. It is a no-op on a nil report.
+func (r *runReport) addInputs(paths []string) {
+	if r == nil {
+		return
+	}
+	r.Inputs = dedupe(append(r.Inputs, paths...))
+	r.Counts.Inputs = len(r.Inputs)
+}
+
+// record records the outcome of generating a CRD. It is a no-op on a nil
+// report.
+func (r *runReport) record(f *fileReport, d time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	f.DurationMs = d.Milliseconds()
+	switch {
+	case err != nil:
+		f.Status = FileStatusFailed
+		f.Error = err.Error()
+		r.Counts.Failed++
+	case f.Status == FileStatusUpToDate:
+		r.Counts.UpToDate++
+	case f.Status == FileStatusSkipped:
+		r.Counts.Skipped++
+	default:
+		f.Status = FileStatusConverted
+		r.Counts.Converted++
+	}
+	if f.Output != "" {
+		r.Outputs = append(r.Outputs, f.Output)
+	}
+	r.Counts.Warnings += len(f.Warnings)
+	r.Files = append(r.Files, f)
+}
+
+// warn records a warning that does not concern a single file. It is a no-op
+// on a nil report.
+func (r *runReport) warn(w string) {
+	if r == nil {
+		return
+	}
+	r.Warnings = append(r.Warnings, w)
+	r.Counts.Warnings++
+}
+
+// fail records an error that does not concern a single file. It is a no-op on
+// a nil report.
+func (r *runReport) fail(err error) {
+	if r == nil {
+		return
+	}
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// write writes the report as indented JSON to the supplied path.
+func (r *runReport) write(path string) error {
+	r.DurationMs = time.Since(r.Started).Milliseconds()
+	j, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, errWriteReport)
+	}
+	return errors.Wrap(ioutil.WriteFile(path, append(j, '\n'), 0644), errWriteReport)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunReport(t *testing.T) {
+	other := strings.NewReplacer("XThing", "XOther", "xthings", "xothers").Replace(baseXRD)
+	dir := t.TempDir()
+	paths := writeFiles(t, dir,
+		[2]string{"a/xrd.yaml", claimXRD},
+		[2]string{"b/xrd.yaml", other},
+		[2]string{"c/xrd.yaml", "kind: [broken"},
+	)
+	cfg := testConfig()
+	cfg.output = filepath.Join(dir, "crds")
+	cfg.continueOnError = true
+	if err := generateCrdsForFiles(context.Background(), paths, dir, cfg); err == nil {
+		t.Fatal("generateCrdsForFiles(...): got nil error for a run with a broken definition")
+	}
+
+	output := filepath.Join(dir, "report.json")
+	if err := cfg.report.write(output); err != nil {
+		t.Fatalf("write(...): %v", err)
+	}
+	j, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &runReport{}
+	if err := json.Unmarshal(j, got); err != nil {
+		t.Fatalf("write(...): report is not JSON: %v", err)
+	}
+
+	type entry struct{ Input, CRD, Status string }
+	wantFiles := []entry{
+		{paths[2], "", FileStatusFailed},
+		{paths[0], "xthings.example.org", FileStatusConverted},
+		{paths[1], "xothers.example.org", FileStatusConverted},
+		{paths[0], "things.example.org", FileStatusConverted},
+		{paths[1], "", FileStatusSkipped},
+	}
+	var gotFiles []entry
+	for _, f := range got.Files {
+		gotFiles = append(gotFiles, entry{f.Input, f.CRD, f.Status})
+	}
+	if diff := cmp.Diff(wantFiles, gotFiles); diff != "" {
+		t.Errorf("\nEach CRD generated, skipped or failed should be reported per file.\n-want, +got:\n%s", diff)
+	}
+
+	wantCounts := reportCounts{Inputs: 3, Converted: 3, Failed: 1, Skipped: 1}
+	if diff := cmp.Diff(wantCounts, got.Counts); diff != "" {
+		t.Errorf("\nThe outcomes of the run should be counted, each file that fails to load only once.\n-want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(paths, got.Inputs); diff != "" {
+		t.Errorf("\nEvery input should be reported.\n-want, +got:\n%s", diff)
+	}
+	if got.Version != ReportVersion || len(got.Outputs) != 3 {
+		t.Errorf("\nThe report should carry its version and every output.\ngot version %q and outputs %v", got.Version, got.Outputs)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// SARIF versions written by --sarif.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIF rule IDs of conversion, rather than lint, findings.
+const (
+	SarifRuleConversionWarning = "conversion-warning"
+	SarifRuleConversionError   = "conversion-error"
+)
+
+const (
+	errWriteSarif = "cannot write SARIF report"
+)
+
+// sarifLog is a SARIF report of lint and conversion findings. Only the parts
+// of the format that code scanning tools need are modelled.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func newSarifLog() *sarifLog {
+	return &sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "xrdconvert", Rules: []sarifRule{}}},
+			Results: []sarifResult{},
+		}},
+	}
+}
+
+// add records a finding about the supplied file. The line of the supplied
+// field is looked up in the supplied document of the file, if a field is
+// supplied. It is a no-op on a nil log.
+func (l *sarifLog) add(rule, level, path string, doc int, field, message string) {
+	if l == nil {
+		return
+	}
+	loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: sarifURI(path)}}
+	if line := fieldLine(path, doc, field); line > 0 {
+		loc.Region = &sarifRegion{StartLine: line}
+	}
+	l.Runs[0].Results = append(l.Runs[0].Results, sarifResult{
+		RuleID:    rule,
+		Level:     level,
+		Message:   sarifMessage{Text: message},
+		Locations: []sarifLocation{{PhysicalLocation: loc}},
+	})
+}
+
+// addFinding records a lint finding. It is a no-op on a nil log.
+func (l *sarifLog) addFinding(f lintFinding) {
+	l.add(f.Rule, "warning", f.Path, f.Document, f.Field, f.Message)
+}
+
+// addFileReport records the warnings and error of generating a CRD. It is a
+// no-op on a nil log.
+func (l *sarifLog) addFileReport(f *fileReport, err error) {
+	for _, w := range f.Warnings {
+		l.add(SarifRuleConversionWarning, "warning", f.Input, 0, "", w)
+	}
+	if err != nil {
+		l.add(SarifRuleConversionError, "error", f.Input, 0, "", err.Error())
+	}
+}
+
+// write writes the log as indented JSON to the supplied path.
+func (l *sarifLog) write(path string) error {
+	seen := map[string]bool{}
+	for _, r := range l.Runs[0].Results {
+		seen[r.RuleID] = true
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		l.Runs[0].Tool.Driver.Rules = append(l.Runs[0].Tool.Driver.Rules, sarifRule{ID: id})
+	}
+
+	j, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, errWriteSarif)
+	}
+	return errors.Wrap(ioutil.WriteFile(path, append(j, '\n'), 0644), errWriteSarif)
+}
+
+// sarifURI returns the supplied path relative to the working directory, as
+// code scanning tools expect, if it is beneath it.
+func sarifURI(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(cwd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// fieldLine returns the line of the definition in the supplied document of the
+// file at the supplied path on which a field, such as
+// v1alpha1:spec.parameters.zones[].name, is declared. A bare version name
+// returns the line of the version. It returns zero if the field cannot be
+// found.
+func fieldLine(path string, index int, field string) int {
+	if field == "" {
+		return 0
+	}
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	docs, err := decodeNodes(y)
+	if err != nil || index >= len(docs) {
+		return 0
+	}
+	doc := docs[index]
+
+	version, rest, _ := strings.Cut(field, ":")
+	versions := mappingNode(doc, "spec", "versions")
+	if versions == nil || versions.Kind != yamlv3.SequenceNode {
+		return 0
+	}
+	var v *yamlv3.Node
+	for _, n := range versions.Content {
+		if mappingValue(n, "name") == version {
+			v = n
+		}
+	}
+	if v == nil {
+		return 0
+	}
+	if rest == "" {
+		return v.Line
+	}
+
+	n := mappingNode(v, "schema", "openAPIV3Schema")
+	line := 0
+	for _, f := range strings.Split(rest, ".") {
+		name := strings.TrimSuffix(f, "[]")
+		props := mappingNode(n, "properties")
+		if props == nil || props.Kind != yamlv3.MappingNode {
+			return line
+		}
+		n = nil
+		for i := 0; i+1 < len(props.Content); i += 2 {
+			if props.Content[i].Value == name {
+				line, n = props.Content[i].Line, props.Content[i+1]
+			}
+		}
+		if n == nil {
+			return line
+		}
+		if f != name {
+			if n = mappingNode(n, "items"); n == nil {
+				return line
+			}
+		}
+	}
+	return line
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func TestFieldLine(t *testing.T) {
+	// The overlay definition starts on line 25, after the base definition and
+	// its separator.
+	stream := baseXRD + "---\n" + overlayXRD
+
+	cases := map[string]struct {
+		reason string
+		index  int
+		field  string
+		want   int
+	}{
+		"Version": {
+			reason: "A bare version name should return the line of the version.",
+			field:  "v1",
+			want:   11,
+		},
+		"Field": {
+			reason: "A field should return the line on which it is declared.",
+			field:  "v1:spec.base",
+			want:   21,
+		},
+		"LaterDocument": {
+			reason: "A field of a later document should be looked up in that document.",
+			index:  1,
+			field:  "v1:spec.overlay",
+			want:   41,
+		},
+		"MissingField": {
+			reason: "A field the document does not declare should return the line of its closest declared parent.",
+			index:  1,
+			field:  "v1:spec.base",
+			want:   38,
+		},
+		"MissingDocument": {
+			reason: "A document the file does not hold should return zero.",
+			index:  2,
+			field:  "v1:spec.base",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := writeFiles(t, t.TempDir(), [2]string{"xrd.yaml", stream})[0]
+			if got := fieldLine(p, tc.index, tc.field); got != tc.want {
+				t.Errorf("\n%s\nfieldLine(..., %d, %q): got %d, want %d", tc.reason, tc.index, tc.field, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFieldLineOfFinding(t *testing.T) {
+	// A comment-only document counts as a document, so the overlay definition
+	// is the third document, and its spec.overlay field is on line 43.
+	p := writeFiles(t, t.TempDir(), [2]string{"xrd.yaml", "# Things and their overlay.\n---\n" + baseXRD + "---\n" + overlayXRD})[0]
+
+	cfg := testConfig()
+	cfg.lintRules = []string{"field-description"}
+	findings, err := lintPaths(context.Background(), []string{p}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, f := range findings {
+		got[fmt.Sprintf("%d %s", f.Document, f.Field)] = fieldLine(p, f.Document, f.Field)
+	}
+	want := map[string]int{"1 v1:spec.base": 23, "2 v1:spec.overlay": 43}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("fieldLine(...): -want, +got lines of findings:\n%s", diff)
+	}
+}
+
+func TestSarifLog(t *testing.T) {
+	dir := t.TempDir()
+	p := writeFiles(t, dir, [2]string{"xrd.yaml", baseXRD + "---\n" + overlayXRD})[0]
+
+	cfg := testConfig()
+	cfg.lintRules = []string{"field-description"}
+	findings, err := lintPaths(context.Background(), []string{p}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newSarifLog()
+	for _, f := range findings {
+		l.addFinding(f)
+	}
+	l.addFileReport(&fileReport{Input: p, Warnings: []string{"deprecated"}}, errors.New("boom"))
+
+	output := filepath.Join(dir, "out.sarif")
+	if err := l.write(output); err != nil {
+		t.Fatalf("write(...): %v", err)
+	}
+	j, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &sarifLog{}
+	if err := json.Unmarshal(j, got); err != nil {
+		t.Fatalf("write(...): report is not JSON: %v", err)
+	}
+
+	type result struct {
+		Rule, URI string
+		Line      int
+	}
+	uri := sarifURI(p)
+	want := []result{
+		{"field-description", uri, 21},
+		{"field-description", uri, 41},
+		{SarifRuleConversionWarning, uri, 0},
+		{SarifRuleConversionError, uri, 0},
+	}
+	var results []result
+	for _, r := range got.Runs[0].Results {
+		line := 0
+		if reg := r.Locations[0].PhysicalLocation.Region; reg != nil {
+			line = reg.StartLine
+		}
+		results = append(results, result{r.RuleID, r.Locations[0].PhysicalLocation.ArtifactLocation.URI, line})
+	}
+	if diff := cmp.Diff(want, results); diff != "" {
+		t.Errorf("\nFindings should be reported with their rule and location in the source definition.\n-want, +got:\n%s", diff)
+	}
+	wantRules := []sarifRule{{ID: SarifRuleConversionError}, {ID: SarifRuleConversionWarning}, {ID: "field-description"}}
+	if diff := cmp.Diff(wantRules, got.Runs[0].Tool.Driver.Rules); diff != "" {
+		t.Errorf("\nEvery rule with a finding should be declared once.\n-want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// Annotations that enable the scale subresource on the CRDs derived from an
+// XRD. They take precedence over WithScale.
+const (
+	AnnotationScaleSpecReplicasPath   = "xrdconvert.dev/scale-spec-replicas-path"
+	AnnotationScaleStatusReplicasPath = "xrdconvert.dev/scale-status-replicas-path"
+	AnnotationScaleLabelSelectorPath  = "xrdconvert.dev/scale-label-selector-path"
+)
+
+const (
+	errScaleIncomplete   = "scale subresource requires both a spec and a status replicas path"
+	errFmtScalePath      = "scale path %q of version %s"
+	errFmtScalePathField = "scale path %q must start with .spec or .status"
+)
+
+// scaleFor returns the scale subresource of the CRDs derived from the supplied
+// XRD, or nil if they should have none.
+func scaleFor(xrd *v1.CompositeResourceDefinition, o *options) (*extv1.CustomResourceSubresourceScale, error) {
+	s := &extv1.CustomResourceSubresourceScale{}
+	if o.scale != nil {
+		s = o.scale.DeepCopy()
+	}
+	a := xrd.GetAnnotations()
+	if p, ok := a[AnnotationScaleSpecReplicasPath]; ok {
+		s.SpecReplicasPath = p
+	}
+	if p, ok := a[AnnotationScaleStatusReplicasPath]; ok {
+		s.StatusReplicasPath = p
+	}
+	if p, ok := a[AnnotationScaleLabelSelectorPath]; ok {
+		s.LabelSelectorPath = &p
+	}
+
+	switch {
+	case s.SpecReplicasPath == "" && s.StatusReplicasPath == "" && s.LabelSelectorPath == nil:
+		return nil, nil
+	case s.SpecReplicasPath == "" || s.StatusReplicasPath == "":
+		return nil, errors.New(errScaleIncomplete)
+	}
+	return s, nil
+}
+
+// setScale sets the supplied scale subresource on the supplied version, after
+// checking that each of its paths exists in the version's schema.
+func setScale(v *extv1.CustomResourceDefinitionVersion, s *extv1.CustomResourceSubresourceScale) error {
+	if s == nil {
+		return nil
+	}
+	paths := []string{s.SpecReplicasPath, s.StatusReplicasPath}
+	if s.LabelSelectorPath != nil {
+		paths = append(paths, *s.LabelSelectorPath)
+	}
+	for _, p := range paths {
+		field := strings.TrimPrefix(p, ".")
+		if !strings.HasPrefix(field, "spec.") && !strings.HasPrefix(field, "status.") {
+			return errors.Errorf(errFmtScalePathField, p)
+		}
+		if _, err := schemaAtPath(v.Schema.OpenAPIV3Schema, field); err != nil {
+			return errors.Wrapf(err, errFmtScalePath, p, v.Name)
+		}
+	}
+	v.Subresources.Scale = s.DeepCopy()
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestScale(t *testing.T) {
+	scaleXRD := baseXRD + `              replicas:
+                type: integer
+          status:
+            type: object
+            properties:
+              replicas:
+                type: integer
+              selector:
+                type: string
+`
+	annotated := strings.Replace(scaleXRD, "  name: xthings.example.org\n", `  name: xthings.example.org
+  annotations:
+    xrdconvert.dev/scale-spec-replicas-path: .spec.replicas
+    xrdconvert.dev/scale-status-replicas-path: .status.replicas
+`, 1)
+
+	cases := map[string]struct {
+		reason   string
+		xrd      string
+		spec     string
+		status   string
+		selector string
+		want     *extv1.CustomResourceSubresourceScale
+		wantErr  string
+	}{
+		"NoScale": {
+			reason: "CRDs should have no scale subresource unless asked.",
+			xrd:    scaleXRD,
+		},
+		"Flags": {
+			reason:   "The scale subresource should be emitted with the configured paths.",
+			xrd:      scaleXRD,
+			spec:     ".spec.replicas",
+			status:   ".status.replicas",
+			selector: ".status.selector",
+			want: &extv1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.replicas",
+				StatusReplicasPath: ".status.replicas",
+				LabelSelectorPath:  pointer.String(".status.selector"),
+			},
+		},
+		"Annotations": {
+			reason: "The scale subresource should be emitted with the paths the definition's annotations configure.",
+			xrd:    annotated,
+			want: &extv1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.replicas",
+				StatusReplicasPath: ".status.replicas",
+			},
+		},
+		"Incomplete": {
+			reason:  "A scale subresource without a status replicas path should be rejected.",
+			xrd:     scaleXRD,
+			spec:    ".spec.replicas",
+			wantErr: errScaleIncomplete,
+		},
+		"MissingPath": {
+			reason:  "A path that does not exist in the schema should be rejected.",
+			xrd:     scaleXRD,
+			spec:    ".spec.size",
+			status:  ".status.replicas",
+			wantErr: `scale path ".spec.size" of version v1`,
+		},
+		"NotSpecOrStatus": {
+			reason:  "A path outside spec and status should be rejected.",
+			xrd:     scaleXRD,
+			spec:    ".metadata.replicas",
+			status:  ".status.replicas",
+			wantErr: `scale path ".metadata.replicas" must start with .spec or .status`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.scaleSpecReplicasPath = tc.spec
+			cfg.scaleStatusReplicasPath = tc.status
+			cfg.scaleLabelSelectorPath = tc.selector
+			d, err := decodeXrd([]byte(tc.xrd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd, err := compositeGenerator(cfg.compositeOptions()...)(d)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("\n%s\nForCompositeResource(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("\n%s\nForCompositeResource(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			case err != nil:
+				return
+			}
+			if diff := cmp.Diff(tc.want, crd.Spec.Versions[0].Subresources.Scale); diff != "" {
+				t.Errorf("\n%s\nForCompositeResource(...): -want, +got scale subresource:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
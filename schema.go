@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	errPrintSchemaUsage = "usage: print-schema <definition> <field path>"
+	errFmtNoSuchField   = "schema has no field %q"
+	errNoVersions       = "definition has no versions"
+	errMarshalSchema    = "cannot marshal schema"
+	errReadBaseSchema   = "cannot read base schema"
+	errParseBaseSchema  = "cannot parse base schema"
+	errReadColumns      = "cannot read printer columns"
+	errParseColumns     = "cannot parse printer columns"
+
+	errFmtInvalidDefault = "default of field %q is not valid JSON"
+)
+
+// schemaAtPath returns the schema at the supplied dot separated field path,
+// e.g. spec.parameters.size. Array schemas are traversed through their items.
+func schemaAtPath(s *extv1.JSONSchemaProps, path string) (*extv1.JSONSchemaProps, error) {
+	if path == "" {
+		return s, nil
+	}
+	for _, f := range strings.Split(path, ".") {
+		for s.Items != nil && s.Items.Schema != nil {
+			s = s.Items.Schema
+		}
+		p, ok := s.Properties[f]
+		if !ok {
+			return nil, errors.Errorf(errFmtNoSuchField, f)
+		}
+		s = &p
+	}
+	return s, nil
+}
+
+// removeAtPath removes the field at the supplied dot separated path from the
+// supplied schema, along with its entry in its parent's required fields. It
+// returns false if there is no such field.
+func removeAtPath(s *extv1.JSONSchemaProps, path string) bool {
+	fields := strings.Split(path, ".")
+	for s.Items != nil && s.Items.Schema != nil {
+		s = s.Items.Schema
+	}
+	p, ok := s.Properties[fields[0]]
+	if !ok {
+		return false
+	}
+	if len(fields) == 1 {
+		delete(s.Properties, fields[0])
+		s.Required = without(s.Required, fields[0])
+		return true
+	}
+	if !removeAtPath(&p, strings.Join(fields[1:], ".")) {
+		return false
+	}
+	s.Properties[fields[0]] = p
+	return true
+}
+
+// stripPaths removes the fields at the supplied paths from every version of
+// the supplied CRD. It returns the paths that were not found in any version.
+func stripPaths(crd *extv1.CustomResourceDefinition, paths []string) []string {
+	var missing []string
+	for _, path := range paths {
+		found := false
+		for _, v := range crd.Spec.Versions {
+			if v.Schema != nil && v.Schema.OpenAPIV3Schema != nil && removeAtPath(v.Schema.OpenAPIV3Schema, path) {
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// without returns s without any occurrence of v.
+func without(s []string, v string) []string {
+	out := make([]string, 0, len(s))
+	for _, e := range s {
+		if e != v {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// loadBaseSchema reads an OpenAPI v3 schema whose spec and status properties
+// are shared by every version of every definition.
+func loadBaseSchema(path string) (*extv1.JSONSchemaProps, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadBaseSchema)
+	}
+	s := &extv1.JSONSchemaProps{}
+	if err := yaml.Unmarshal(y, s); err != nil {
+		return nil, errors.Wrap(err, errParseBaseSchema)
+	}
+	return s, nil
+}
+
+// loadPrinterColumns reads a list of printer columns shared by every version of
+// every definition.
+func loadPrinterColumns(path string) ([]extv1.CustomResourceColumnDefinition, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadColumns)
+	}
+	var cols []extv1.CustomResourceColumnDefinition
+	if err := yaml.Unmarshal(y, &cols); err != nil {
+		return nil, errors.Wrap(err, errParseColumns)
+	}
+	return cols, nil
+}
+
+// withBaseProps returns the supplied properties and required fields of the
+// named top-level field merged over those of the base schema, if any.
+func withBaseProps(base *extv1.JSONSchemaProps, field string, props map[string]extv1.JSONSchemaProps, required []string) (map[string]extv1.JSONSchemaProps, []string) {
+	if base == nil {
+		return props, required
+	}
+	b, ok := base.Properties[field]
+	if !ok {
+		return props, required
+	}
+
+	merged := make(map[string]extv1.JSONSchemaProps, len(b.Properties)+len(props))
+	for k, v := range b.Properties {
+		merged[k] = *v.DeepCopy()
+	}
+	for k, v := range props {
+		merged[k] = v
+	}
+	return merged, dedupe(append(append([]string{}, b.Required...), required...))
+}
+
+// storageVersion returns the storage version of the supplied CRD, or its first
+// version if none is marked for storage.
+func storageVersion(crd *extv1.CustomResourceDefinition) (*extv1.CustomResourceDefinitionVersion, error) {
+	if len(crd.Spec.Versions) == 0 {
+		return nil, errors.New(errNoVersions)
+	}
+	for i := range crd.Spec.Versions {
+		if crd.Spec.Versions[i].Storage {
+			return &crd.Spec.Versions[i], nil
+		}
+	}
+	return &crd.Spec.Versions[0], nil
+}
+
+// printSchema writes the schema at a field path of the composite resource CRD
+// generated from a definition file. It expects the definition file and field
+// path as arguments.
+func printSchema(w io.Writer, args []string, cfg *config) error {
+	if len(args) != 2 {
+		return errors.New(errPrintSchemaUsage)
+	}
+
+	xrd, err := loadXrd(args[0])
+	if err != nil {
+		return errors.Wrapf(err, errFmtLoadXrd, args[0])
+	}
+	crd, err := ForCompositeResource(xrd.CompositeResourceDefinition, xrd.compositeOptions(cfg.compositeOptions())...)
+	if err != nil {
+		return err
+	}
+	v, err := storageVersion(crd)
+	if err != nil {
+		return err
+	}
+	s, err := schemaAtPath(v.Schema.OpenAPIV3Schema, args[1])
+	if err != nil {
+		return err
+	}
+
+	y, err := yaml.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, errMarshalSchema)
+	}
+	_, err = fmt.Fprint(w, string(y))
+	return err
+}
+
+// checkDefaults returns an error naming the first field under the supplied
+// properties whose default did not convert to valid JSON. Defaults written as
+// YAML maps and arrays are converted along with the rest of the definition,
+// so this only guards against them being mangled on the way.
+func checkDefaults(prefix string, props map[string]extv1.JSONSchemaProps) error {
+	for _, k := range sortedKeys(props) {
+		s := props[k]
+		field := prefix + "." + k
+		if s.Default != nil && len(s.Default.Raw) > 0 && !json.Valid(s.Default.Raw) {
+			return errors.Errorf(errFmtInvalidDefault, field)
+		}
+		if err := checkDefaults(field, s.Properties); err != nil {
+			return err
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			if err := checkDefaults(field+"[]", s.Items.Schema.Properties); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// withUserFacets returns the supplied generated spec or status schema with
+// every keyword of the supplied user schema, such as its description,
+// minProperties or x-kubernetes-validations, except its type, properties and
+// required fields. The generated schema's own type, properties and required
+// fields are kept, so that user and Crossplane properties can be merged into
+// them.
+func withUserFacets(generated, user extv1.JSONSchemaProps) extv1.JSONSchemaProps {
+	out := *user.DeepCopy()
+	out.Type = generated.Type
+	out.Properties = generated.Properties
+	out.Required = generated.Required
+	return out
+}
+
+// sortRequired sorts the required fields of the supplied schema and of every
+// schema nested in it, so that generated CRDs are byte-stable.
+func sortRequired(s *extv1.JSONSchemaProps) {
+	if s == nil {
+		return
+	}
+	sort.Strings(s.Required)
+	for k, p := range s.Properties {
+		sortRequired(&p)
+		s.Properties[k] = p
+	}
+	if s.Items != nil {
+		sortRequired(s.Items.Schema)
+		for i := range s.Items.JSONSchemas {
+			sortRequired(&s.Items.JSONSchemas[i])
+		}
+	}
+	if s.AdditionalProperties != nil {
+		sortRequired(s.AdditionalProperties.Schema)
+	}
+	for _, of := range [][]extv1.JSONSchemaProps{s.AllOf, s.AnyOf, s.OneOf} {
+		for i := range of {
+			sortRequired(&of[i])
+		}
+	}
+	sortRequired(s.Not)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestPrintSchema(t *testing.T) {
+	xrd := strings.Replace(baseXRD, `              base:
+                type: string
+`, `              parameters:
+                type: object
+                properties:
+                  size:
+                    type: integer
+                    minimum: 1
+              zones:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+`, 1)
+
+	cases := map[string]struct {
+		reason  string
+		path    string
+		extra   []string
+		want    string
+		wantErr string
+	}{
+		"Nested": {
+			reason: "The schema of a nested field should be printed.",
+			path:   "spec.parameters.size",
+			want:   "minimum: 1\ntype: integer\n",
+		},
+		"ThroughArray": {
+			reason: "Array fields should be traversed through their items.",
+			path:   "spec.zones.name",
+			want:   "type: string\n",
+		},
+		"Missing": {
+			reason:  "A field the schema lacks should be reported.",
+			path:    "spec.parameters.colour",
+			wantErr: `schema has no field "colour"`,
+		},
+		"Usage": {
+			reason:  "Extra arguments should be rejected.",
+			path:    "spec",
+			extra:   []string{"status"},
+			wantErr: errPrintSchemaUsage,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := writeFiles(t, t.TempDir(), [2]string{"xrd.yaml", xrd})[0]
+			buf := &bytes.Buffer{}
+			err := printSchema(buf, append([]string{p, tc.path}, tc.extra...), testConfig())
+			switch {
+			case tc.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("\n%s\nprintSchema(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+				}
+			case err != nil:
+				t.Errorf("\n%s\nprintSchema(...): %v", tc.reason, err)
+			case buf.String() != tc.want:
+				t.Errorf("\n%s\nprintSchema(...): got %q, want %q", tc.reason, buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestStripPaths(t *testing.T) {
+	xrd := strings.Replace(baseXRD, `            properties:
+              base:
+                type: string
+`, `            required: [parameters]
+            properties:
+              base:
+                type: string
+              parameters:
+                type: object
+                required: [secret, size]
+                properties:
+                  secret:
+                    type: string
+                  size:
+                    type: integer
+`, 1)
+
+	cases := map[string]struct {
+		reason       string
+		paths        []string
+		wantMissing  []string
+		wantRequired map[string][]string
+	}{
+		"Nested": {
+			reason:       "A stripped field should be absent, along with its required entry.",
+			paths:        []string{"spec.parameters.secret"},
+			wantRequired: map[string][]string{"spec": {"parameters"}, "spec.parameters": {"size"}},
+		},
+		"Object": {
+			reason:       "A stripped object field should be absent, along with its required entry.",
+			paths:        []string{"spec.parameters"},
+			wantRequired: map[string][]string{"spec": {}},
+		},
+		"Missing": {
+			reason:       "A path the schema lacks should be reported.",
+			paths:        []string{"spec.parameters.colour"},
+			wantMissing:  []string{"spec.parameters.colour"},
+			wantRequired: map[string][]string{"spec": {"parameters"}, "spec.parameters": {"secret", "size"}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := deriveCRDs(t, xrd, testConfig())[0]
+			missing := stripPaths(crd, tc.paths)
+			if diff := cmp.Diff(tc.wantMissing, missing); diff != "" {
+				t.Errorf("\n%s\nstripPaths(...): -want, +got missing paths:\n%s", tc.reason, diff)
+			}
+
+			root := crd.Spec.Versions[0].Schema.OpenAPIV3Schema
+			for _, p := range tc.paths {
+				if _, err := schemaAtPath(root, p); err == nil {
+					t.Errorf("\n%s\nstripPaths(...): %s is still present", tc.reason, p)
+				}
+			}
+			for p, want := range tc.wantRequired {
+				s, err := schemaAtPath(root, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(want, s.Required, cmpopts.EquateEmpty()); diff != "" {
+					t.Errorf("\n%s\nstripPaths(...): -want, +got required fields of %s:\n%s", tc.reason, p, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestYAMLDefaults(t *testing.T) {
+	defaults := baseXRD + `              tags:
+                type: object
+                additionalProperties:
+                  type: string
+                default:
+                  team: platform
+                  env: dev
+              zones:
+                type: array
+                items:
+                  type: string
+                default: [a, b]
+              nested:
+                type: object
+                default:
+                  inner: {count: 1, flags: [true, null]}
+                properties:
+                  inner:
+                    type: object
+                    x-kubernetes-preserve-unknown-fields: true
+`
+	want := map[string]interface{}{
+		"tags":   map[string]interface{}{"team": "platform", "env": "dev"},
+		"zones":  []interface{}{"a", "b"},
+		"nested": map[string]interface{}{"inner": map[string]interface{}{"count": float64(1), "flags": []interface{}{true, nil}}},
+	}
+
+	crd := deriveCRDs(t, defaults, testConfig())[0]
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	got := map[string]interface{}{}
+	for f := range want {
+		var v interface{}
+		if err := json.Unmarshal(spec.Properties[f].Default.Raw, &v); err != nil {
+			t.Fatalf("\nYAML defaults should convert to valid JSON.\n%s: %v", f, err)
+		}
+		got[f] = v
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nMap and array defaults should survive conversion intact.\n-want, +got:\n%s", diff)
+	}
+}
+
+func TestCheckDefaults(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		props   map[string]extv1.JSONSchemaProps
+		wantErr string
+	}{
+		"Valid": {
+			reason: "Valid JSON defaults should be accepted.",
+			props: map[string]extv1.JSONSchemaProps{
+				"zones": {Type: "array", Default: &extv1.JSON{Raw: []byte(`["a","b"]`)}},
+			},
+		},
+		"Malformed": {
+			reason: "A malformed default should be reported with its field.",
+			props: map[string]extv1.JSONSchemaProps{
+				"zones": {Type: "array", Default: &extv1.JSON{Raw: []byte(`[a, b]`)}},
+			},
+			wantErr: `default of field "spec.zones" is not valid JSON`,
+		},
+		"MalformedNested": {
+			reason: "A malformed default beneath array items should be reported with its field.",
+			props: map[string]extv1.JSONSchemaProps{
+				"items": {Type: "array", Items: &extv1.JSONSchemaPropsOrArray{Schema: &extv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"tags": {Type: "object", Default: &extv1.JSON{Raw: []byte(`{team: platform}`)}},
+					},
+				}}},
+			},
+			wantErr: `default of field "spec.items[].tags" is not valid JSON`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkDefaults("spec", tc.props)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.wantErr {
+				t.Errorf("\n%s\ncheckDefaults(...): got error %q, want %q", tc.reason, got, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// APIVersionV2 is the API version of Crossplane v2 XRDs. Their schema is a
+// superset of the v1 schema that adds the scope of composite resources.
+const APIVersionV2 = "apiextensions.crossplane.io/v2"
+
+// Scopes of the composite resources a v2 XRD defines.
+const (
+	ScopeNamespaced    = "Namespaced"
+	ScopeCluster       = "Cluster"
+	ScopeLegacyCluster = "LegacyCluster"
+)
+
+const (
+	errFmtUnknownScope = "unknown scope %q, want Namespaced, Cluster or LegacyCluster"
+)
+
+// readScope returns the scope declared by the supplied v2 XRD document. v2
+// XRDs define namespaced composite resources unless they declare otherwise.
+func readScope(doc []byte) (string, error) {
+	v2 := struct {
+		Spec struct {
+			Scope string `json:"scope"`
+		} `json:"spec"`
+	}{}
+	if err := yaml.Unmarshal(doc, &v2); err != nil {
+		return "", err
+	}
+	if v2.Spec.Scope == "" {
+		return ScopeNamespaced, nil
+	}
+	return v2.Spec.Scope, nil
+}
+
+// scopeFor returns the scope of the composite resource CRD derived using the
+// supplied options.
+func scopeFor(o *options) (extv1.ResourceScope, error) {
+	switch s := o.scope; s {
+	case "", ScopeCluster, ScopeLegacyCluster:
+		return extv1.ClusterScoped, nil
+	case ScopeNamespaced:
+		return extv1.NamespaceScoped, nil
+	default:
+		return "", errors.Errorf(errFmtUnknownScope, s)
+	}
+}
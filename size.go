@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+)
+
+// defaultSizeLimit is the serialized size above which a generated CRD is
+// considered at risk of exceeding etcd's request size limit once it is stored
+// alongside its managed fields and last-applied annotation.
+const defaultSizeLimit = 256 * 1024
+
+// reportSize prints the serialized size of the named CRD, warning when it is
+// larger than limit.
+func reportSize(name string, size, limit int) {
+	fmt.Fprintf(messages, "%s: %d bytes\n", name, size)
+	if limit > 0 && size > limit {
+		fmt.Fprintf(messages, "Warning: %s is %d bytes, exceeding the %d byte limit\n", name, size, limit)
+	}
+}
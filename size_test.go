@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReportSize(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		size   int
+		limit  int
+		want   string
+	}{
+		"WithinLimit": {
+			reason: "A CRD within the limit should only have its size reported.",
+			size:   10,
+			limit:  20,
+			want:   "crd: 10 bytes\n",
+		},
+		"OverLimit": {
+			reason: "A CRD over the limit should be warned about.",
+			size:   30,
+			limit:  20,
+			want:   "crd: 30 bytes\nWarning: crd is 30 bytes, exceeding the 20 byte limit\n",
+		},
+		"NoLimit": {
+			reason: "A CRD should not be warned about when there is no limit.",
+			size:   30,
+			want:   "crd: 30 bytes\n",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			defer func(w io.Writer) { messages = w }(messages)
+			messages = buf
+
+			reportSize("crd", tc.size, tc.limit)
+			if got := buf.String(); got != tc.want {
+				t.Errorf("\n%s\nreportSize(...): got %q, want %q", tc.reason, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderCrdReportsSize(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		limit    int
+		wantWarn bool
+	}{
+		"Small": {
+			reason: "A generated CRD within the limit should have its size reported without a warning.",
+			limit:  defaultSizeLimit,
+		},
+		"Oversized": {
+			reason:   "A generated CRD over the limit should be warned about.",
+			limit:    100,
+			wantWarn: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			defer func(w io.Writer) { messages = w }(messages)
+			messages = buf
+
+			xrd, err := decodeXrd([]byte(baseXRD))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := testConfig()
+			cfg.reportSize = true
+			cfg.sizeLimit = tc.limit
+			_, y, err := renderCrd(context.Background(), "xrd.yaml", xrd, cfg, compositeGenerator(), &fileReport{})
+			if err != nil {
+				t.Fatalf("\n%s\nrenderCrd(...): %v", tc.reason, err)
+			}
+
+			got := buf.String()
+			if want := fmt.Sprintf("xthings.example.org: %d bytes\n", len(y)); !strings.HasPrefix(got, want) {
+				t.Errorf("\n%s\nrenderCrd(...): got report %q, want one starting %q", tc.reason, got, want)
+			}
+			if warned := strings.Contains(got, "Warning:"); warned != tc.wantWarn {
+				t.Errorf("\n%s\nrenderCrd(...): warned %t, want %t: %q", tc.reason, warned, tc.wantWarn, got)
+			}
+		})
+	}
+}
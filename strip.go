@@ -0,0 +1,96 @@
+package main
+
+import (
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// StripCrossplaneProps returns a copy of the supplied CRD without the spec and
+// status props, validation rules and printer columns this tool injects for
+// Crossplane, leaving only what the XRD declared. The supplied CRD is not
+// modified.
+func StripCrossplaneProps(crd *extv1.CustomResourceDefinition) *extv1.CustomResourceDefinition {
+	out := crd.DeepCopy()
+
+	spec := map[string]bool{}
+	for k := range CompositeResourceSpecProps() {
+		spec[k] = true
+	}
+	for k := range CompositeResourceClaimSpecProps() {
+		spec[k] = true
+	}
+	status := map[string]bool{}
+	for k := range CompositeResourceStatusProps() {
+		status[k] = true
+	}
+	cols := append(CompositeResourcePrinterColumns(), CompositeResourceClaimPrinterColumns()...)
+
+	for i := range out.Spec.Versions {
+		v := &out.Spec.Versions[i]
+		v.AdditionalPrinterColumns = withoutColumns(v.AdditionalPrinterColumns, cols)
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+		props := v.Schema.OpenAPIV3Schema.Properties
+		if s, ok := props["spec"]; ok {
+			s = withoutProps(s, spec)
+			s.XValidations = withoutRule(s.XValidations, ExclusiveCompositionRefsRule())
+			props["spec"] = s
+		}
+		if s, ok := props["status"]; ok {
+			props["status"] = withoutProps(s, status)
+		}
+	}
+	return out
+}
+
+// withoutProps returns the supplied schema without the named properties, or
+// any requirement for them.
+func withoutProps(s extv1.JSONSchemaProps, names map[string]bool) extv1.JSONSchemaProps {
+	for k := range names {
+		delete(s.Properties, k)
+	}
+	required := make([]string, 0, len(s.Required))
+	for _, r := range s.Required {
+		if !names[r] {
+			required = append(required, r)
+		}
+	}
+	if len(required) == 0 {
+		required = nil
+	}
+	s.Required = required
+	return s
+}
+
+// withoutColumns returns the supplied printer columns without any equal to one
+// of the supplied columns to remove.
+func withoutColumns(cols, remove []extv1.CustomResourceColumnDefinition) []extv1.CustomResourceColumnDefinition {
+	out := make([]extv1.CustomResourceColumnDefinition, 0, len(cols))
+	for _, c := range cols {
+		found := false
+		for _, r := range remove {
+			if c.Name == r.Name && c.JSONPath == r.JSONPath {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// withoutRule returns the supplied validation rules without the supplied rule.
+func withoutRule(rules extv1.ValidationRules, remove extv1.ValidationRule) extv1.ValidationRules {
+	var out extv1.ValidationRules
+	for _, r := range rules {
+		if r.Rule != remove.Rule {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestStripCrossplaneProps(t *testing.T) {
+	xrd := baseXRD + `          status:
+            type: object
+            properties:
+              ready:
+                type: boolean
+    additionalPrinterColumns:
+    - name: BASE
+      type: string
+      jsonPath: .spec.base
+  claimNames:
+    kind: Thing
+    plural: things
+`
+	cfg := testConfig()
+	cfg.exclusiveRefs = true
+	cfg.minKubeVersion = "1.25"
+
+	type remaining struct {
+		Spec, Status []string
+		Columns      []extv1.CustomResourceColumnDefinition
+		Rules        extv1.ValidationRules
+	}
+	want := remaining{
+		Spec:    []string{"base"},
+		Status:  []string{"ready"},
+		Columns: []extv1.CustomResourceColumnDefinition{{Name: "BASE", Type: "string", JSONPath: ".spec.base"}},
+	}
+	for _, crd := range deriveCRDs(t, xrd, cfg) {
+		original := crd.DeepCopy()
+		stripped := StripCrossplaneProps(crd)
+
+		v := stripped.Spec.Versions[0]
+		props := v.Schema.OpenAPIV3Schema.Properties
+		got := remaining{
+			Spec:    sortedKeys(props["spec"].Properties),
+			Status:  sortedKeys(props["status"].Properties),
+			Columns: v.AdditionalPrinterColumns,
+			Rules:   props["spec"].XValidations,
+		}
+		if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("\nOnly the fields, columns and rules the definition declares should remain.\nStripCrossplaneProps(%s): -want, +got:\n%s", crd.GetName(), diff)
+		}
+		if diff := cmp.Diff(original, crd); diff != "" {
+			t.Errorf("\nThe supplied CRD should not be modified.\nStripCrossplaneProps(%s): -want, +got:\n%s", crd.GetName(), diff)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	errFindCompositions     = "cannot find compositions"
+	errFmtLoadXrd           = "cannot load definition %q"
+	errFmtUnusedDefinitions = "%d of %d definitions are unused"
+)
+
+// findCompositions walks dir for YAML files and returns every Composition
+// document found in them. Files that cannot be parsed are ignored; they are not
+// necessarily manifests.
+func findCompositions(ctx context.Context, dir string) ([][]byte, error) {
+	var comps [][]byte
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+		if d.IsDir() || !isYAML(path) {
+			return nil
+		}
+
+		f, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		docs, err := readDocuments(f)
+		if err != nil {
+			return nil
+		}
+		for _, doc := range docs {
+			comp := &v1.Composition{}
+			if err := yaml.Unmarshal(doc, comp); err != nil || comp.Kind != v1.CompositionKind {
+				continue
+			}
+			comps = append(comps, doc)
+		}
+		return nil
+	})
+	return comps, errors.Wrap(err, errFindCompositions)
+}
+
+// findCompositionTypes returns the group and kind of every composite resource
+// type referenced by a Composition under dir.
+func findCompositionTypes(ctx context.Context, dir string) (map[schema.GroupKind]bool, error) {
+	docs, err := findCompositions(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	types := map[schema.GroupKind]bool{}
+	for _, doc := range docs {
+		comp := &v1.Composition{}
+		if err := yaml.Unmarshal(doc, comp); err != nil {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(comp.Spec.CompositeTypeRef.APIVersion)
+		if err != nil {
+			continue
+		}
+		types[schema.GroupKind{Group: gv.Group, Kind: comp.Spec.CompositeTypeRef.Kind}] = true
+	}
+	return types, nil
+}
+
+// reportUnusedDefinitions prints the definitions among paths that no
+// Composition under compositionsDir references, and returns an error if there
+// are any.
+func reportUnusedDefinitions(ctx context.Context, paths []string, compositionsDir string, cfg *config) error {
+	types, err := findCompositionTypes(ctx, compositionsDir)
+	if err != nil {
+		return err
+	}
+
+	total, unused := 0, 0
+	for _, p := range paths {
+		xrds, err := cfg.load(p)
+		if err != nil {
+			return errors.Wrapf(err, errFmtLoadXrd, p)
+		}
+		for _, xrd := range xrds {
+			total++
+			gk := schema.GroupKind{Group: xrd.Spec.Group, Kind: xrd.Spec.Names.Kind}
+			if !types[gk] {
+				fmt.Fprintf(messages, "Unused definition %s: no composition references %s\n", p, gk)
+				unused++
+			}
+		}
+	}
+	if unused > 0 {
+		return errors.Errorf(errFmtUnusedDefinitions, unused, total)
+	}
+	return nil
+}
+
+func isYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportUnusedDefinitions(t *testing.T) {
+	const composition = `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  name: xthings
+spec:
+  compositeTypeRef:
+    apiVersion: example.org/v1
+    kind: XThing
+  resources: []
+`
+	other := strings.NewReplacer("XThing", "XOther", "xthings", "xothers").Replace(baseXRD)
+
+	cases := map[string]struct {
+		reason       string
+		compositions [][2]string
+		want         []string
+		wantErr      bool
+	}{
+		"Orphaned": {
+			reason: "A definition no Composition references should be reported.",
+			compositions: [][2]string{
+				{"comps/xthings.yaml", composition},
+				{"comps/notes.yaml", "not: [a, composition"},
+			},
+			want:    []string{"b/xrd.yaml: no composition references XOther.example.org"},
+			wantErr: true,
+		},
+		"NoCompositions": {
+			reason: "Every definition should be reported when there are no Compositions.",
+			compositions: [][2]string{
+				{"comps/README.md", "# Compositions"},
+			},
+			want: []string{
+				"a/xrd.yaml: no composition references XThing.example.org",
+				"b/xrd.yaml: no composition references XOther.example.org",
+			},
+			wantErr: true,
+		},
+		"AllUsed": {
+			reason: "Nothing should be reported when every definition is referenced.",
+			compositions: [][2]string{
+				{"comps/xthings.yaml", composition},
+				{"comps/xothers.yaml", strings.ReplaceAll(composition, "XThing", "XOther")},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			defer func(w io.Writer) { messages = w }(messages)
+			messages = buf
+
+			dir := t.TempDir()
+			paths := writeFiles(t, dir, [2]string{"a/xrd.yaml", baseXRD}, [2]string{"b/xrd.yaml", other})
+			writeFiles(t, dir, tc.compositions...)
+			err := reportUnusedDefinitions(context.Background(), paths, filepath.Join(dir, "comps"), testConfig())
+			if (err != nil) != tc.wantErr {
+				t.Errorf("\n%s\nreportUnusedDefinitions(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+
+			var got []string
+			if s := strings.TrimSpace(buf.String()); s != "" {
+				got = strings.Split(s, "\n")
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("\n%s\nreportUnusedDefinitions(...): got %d reports, want %d:\n%s", tc.reason, len(got), len(tc.want), buf.String())
+			}
+			for i, w := range tc.want {
+				if !strings.HasSuffix(got[i], w) {
+					t.Errorf("\n%s\nreportUnusedDefinitions(...): got %q, want one ending %q", tc.reason, got[i], w)
+				}
+			}
+		})
+	}
+}
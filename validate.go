@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/validation"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	errConvertInternal = "cannot convert CRD to its internal version"
+	errFmtInvalidCRD   = "generated CRD %q is invalid"
+	errFmtColumnField  = "version %s printer column %d has no %s"
+	errFmtOverridden   = "generated CRD %q replaces user-defined field %s with Crossplane's"
+	errFmtDangerous    = "definition %q uses %s at %s; pass --allow-dangerous-types to allow it"
+)
+
+// versionWarnings returns warnings about the set of versions of the supplied
+// CRD that the API server accepts but that are likely mistakes.
+func versionWarnings(crd *extv1.CustomResourceDefinition) []string {
+	var warnings []string
+
+	if c := crd.Spec.Conversion; c == nil || c.Strategy != extv1.WebhookConverter {
+		var first *extv1.CustomResourceDefinitionVersion
+		for i := range crd.Spec.Versions {
+			v := &crd.Spec.Versions[i]
+			if !v.Served {
+				continue
+			}
+			if first == nil {
+				first = v
+				continue
+			}
+			if !equality.Semantic.DeepEqual(first.Schema, v.Schema) {
+				warnings = append(warnings, fmt.Sprintf("%s: served versions %s and %s have different schemas but no webhook conversion; objects will be converted between them unchanged", crd.GetName(), first.Name, v.Name))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// servesAnyVersion returns true if the supplied CRD serves at least one of its
+// versions. The API server accepts a CRD that serves none, but it is useless.
+func servesAnyVersion(crd *extv1.CustomResourceDefinition) bool {
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			return true
+		}
+	}
+	return false
+}
+
+// selfValidate validates the supplied CRD as the API server would on create,
+// catching any structurally invalid CRD this tool produces.
+func selfValidate(ctx context.Context, crd *extv1.CustomResourceDefinition) error {
+	defaulted := crd.DeepCopy()
+	extv1.SetObjectDefaults_CustomResourceDefinition(defaulted)
+
+	internal := &apiextensions.CustomResourceDefinition{}
+	if err := extv1.Convert_v1_CustomResourceDefinition_To_apiextensions_CustomResourceDefinition(defaulted, internal, nil); err != nil {
+		return errors.Wrap(err, errConvertInternal)
+	}
+
+	if errs := validation.ValidateCustomResourceDefinition(ctx, internal); len(errs) > 0 {
+		return errors.Wrapf(errs.ToAggregate(), errFmtInvalidCRD, crd.GetName())
+	}
+	return nil
+}
+
+// validatePrinterColumns returns an error naming the first printer column of
+// the supplied CRD that lacks a name, type or JSON path.
+func validatePrinterColumns(crd *extv1.CustomResourceDefinition) error {
+	for _, v := range crd.Spec.Versions {
+		for i, c := range v.AdditionalPrinterColumns {
+			switch {
+			case c.Name == "":
+				return errors.Errorf(errFmtColumnField, v.Name, i, "name")
+			case c.Type == "":
+				return errors.Errorf(errFmtColumnField, v.Name, i, "type")
+			case c.JSONPath == "":
+				return errors.Errorf(errFmtColumnField, v.Name, i, "jsonPath")
+			}
+		}
+	}
+	return nil
+}
+
+// validateCRD runs this tool's own checks on the supplied generated CRD.
+func validateCRD(crd *extv1.CustomResourceDefinition) error {
+	return errors.Wrapf(validatePrinterColumns(crd), errFmtInvalidCRD, crd.GetName())
+}
+
+// overriddenStatusFields returns the user-defined status fields of the
+// supplied definition, such as v1alpha1:status.conditions, that differ from
+// the Crossplane status fields that replace them in generated CRDs.
+func overriddenStatusFields(xrd *v1.CompositeResourceDefinition) []string {
+	var fields []string
+	for _, vr := range xrd.Spec.Versions {
+		s := userSchema(vr)
+		if s == nil {
+			continue
+		}
+		user := s.Properties["status"].Properties
+		injected := injectedStatusProps(user, xrd, newOptions(nil))
+		for _, k := range sortedKeys(user) {
+			if c, ok := injected[k]; ok && !equality.Semantic.DeepEqual(user[k], c) {
+				fields = append(fields, vr.Name+":status."+k)
+			}
+		}
+	}
+	return fields
+}
+
+// checkDangerousTypes returns an error if the schema of any version of the
+// supplied definition turns off pruning at its root, or of its spec or status,
+// or allows arbitrary additional properties anywhere. Both let unvalidated
+// data into objects, so some organisations forbid them.
+func checkDangerousTypes(xrd *v1.CompositeResourceDefinition) error {
+	for _, vr := range xrd.Spec.Versions {
+		root := userSchema(vr)
+		if root == nil {
+			continue
+		}
+		if preservesUnknownFields(*root) {
+			return errors.Errorf(errFmtDangerous, xrd.GetName(), "x-kubernetes-preserve-unknown-fields", vr.Name+":openAPIV3Schema")
+		}
+		for _, f := range []string{"spec", "status"} {
+			if preservesUnknownFields(root.Properties[f]) {
+				return errors.Errorf(errFmtDangerous, xrd.GetName(), "x-kubernetes-preserve-unknown-fields", vr.Name+":"+f)
+			}
+		}
+		if field := allowsAdditionalProperties(vr.Name+":", root.Properties); field != "" {
+			return errors.Errorf(errFmtDangerous, xrd.GetName(), "additionalProperties: true", field)
+		}
+	}
+	return nil
+}
+
+func preservesUnknownFields(s extv1.JSONSchemaProps) bool {
+	return s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields
+}
+
+// allowsAdditionalProperties returns the path of the first field under the
+// supplied properties that allows additional properties of any schema, or an
+// empty string if there is none.
+func allowsAdditionalProperties(prefix string, props map[string]extv1.JSONSchemaProps) string {
+	for _, k := range sortedKeys(props) {
+		s := props[k]
+		field := prefix + k
+		if a := s.AdditionalProperties; a != nil && a.Allows && a.Schema == nil {
+			return field
+		}
+		if f := allowsAdditionalProperties(field+".", s.Properties); f != "" {
+			return f
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			if f := allowsAdditionalProperties(field+"[].", s.Items.Schema.Properties); f != "" {
+				return f
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// statusXRD returns a definition of one version whose status declares the
+// supplied props, publishing connection secrets with the supplied keys.
+func statusXRD(t *testing.T, status map[string]extv1.JSONSchemaProps, keys ...string) *v1.CompositeResourceDefinition {
+	t.Helper()
+	raw, err := json.Marshal(extv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]extv1.JSONSchemaProps{
+			"status": {Type: "object", Properties: status},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &v1.CompositeResourceDefinition{
+		Spec: v1.CompositeResourceDefinitionSpec{
+			ConnectionSecretKeys: keys,
+			Versions: []v1.CompositeResourceDefinitionVersion{{
+				Name:   "v1",
+				Schema: &v1.CompositeResourceValidation{OpenAPIV3Schema: runtime.RawExtension{Raw: raw}},
+			}},
+		},
+	}
+}
+
+func TestOverriddenStatusFields(t *testing.T) {
+	crossplane := CompositeResourceStatusProps()
+
+	cases := map[string]struct {
+		reason string
+		status map[string]extv1.JSONSchemaProps
+		keys   []string
+		want   []string
+	}{
+		"UserFields": {
+			reason: "Fields Crossplane does not inject should not be reported.",
+			status: map[string]extv1.JSONSchemaProps{"ready": {Type: "boolean"}},
+		},
+		"IdenticalConditions": {
+			reason: "Fields identical to those Crossplane injects should not be reported.",
+			status: map[string]extv1.JSONSchemaProps{"conditions": crossplane["conditions"]},
+		},
+		"DifferentConditions": {
+			reason: "Fields that differ from those Crossplane injects should be reported.",
+			status: map[string]extv1.JSONSchemaProps{"conditions": {Type: "string"}},
+			want:   []string{"v1:status.conditions"},
+		},
+		"ConnectionSecretKeys": {
+			reason: "Connection details should be compared to those injected for the definition's connection secret keys.",
+			status: map[string]extv1.JSONSchemaProps{"connectionDetails": ConnectionDetailsProps([]string{"url"})},
+			keys:   []string{"url"},
+		},
+		"ConnectionSecretKeysUndocumented": {
+			reason: "Connection details that do not document the definition's connection secret keys should be reported.",
+			status: map[string]extv1.JSONSchemaProps{"connectionDetails": crossplane["connectionDetails"]},
+			keys:   []string{"url"},
+			want:   []string{"v1:status.connectionDetails"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := overriddenStatusFields(statusXRD(t, tc.status, tc.keys...))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\noverriddenStatusFields(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSelfValidate(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		corrupt func(crd *extv1.CustomResourceDefinition)
+		wantErr bool
+	}{
+		"Generated": {
+			reason: "A generated CRD should be valid.",
+		},
+		"WrongName": {
+			reason:  "A CRD not named after its plural and group should be invalid.",
+			corrupt: func(crd *extv1.CustomResourceDefinition) { crd.SetName("wrong.example.org") },
+			wantErr: true,
+		},
+		"NoStorageVersion": {
+			reason: "A CRD without a storage version should be invalid.",
+			corrupt: func(crd *extv1.CustomResourceDefinition) {
+				for i := range crd.Spec.Versions {
+					crd.Spec.Versions[i].Storage = false
+				}
+			},
+			wantErr: true,
+		},
+		"UntypedSchema": {
+			reason:  "A CRD whose schema is not structural should be invalid.",
+			corrupt: func(crd *extv1.CustomResourceDefinition) { crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Type = "" },
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xrd, err := decodeXrd([]byte(baseXRD))
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd, err := ForCompositeResource(xrd.CompositeResourceDefinition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.corrupt != nil {
+				tc.corrupt(crd)
+			}
+			if err := selfValidate(context.Background(), crd); (err != nil) != tc.wantErr {
+				t.Errorf("\n%s\nselfValidate(...): got error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVersionWarnings(t *testing.T) {
+	schema := func(field string) *extv1.CustomResourceValidation {
+		return &extv1.CustomResourceValidation{OpenAPIV3Schema: &extv1.JSONSchemaProps{
+			Type:       "object",
+			Properties: map[string]extv1.JSONSchemaProps{field: {Type: "string"}},
+		}}
+	}
+	version := func(name string, served bool, s *extv1.CustomResourceValidation) extv1.CustomResourceDefinitionVersion {
+		return extv1.CustomResourceDefinitionVersion{Name: name, Served: served, Schema: s}
+	}
+
+	cases := map[string]struct {
+		reason     string
+		versions   []extv1.CustomResourceDefinitionVersion
+		conversion *extv1.CustomResourceConversion
+		want       int
+	}{
+		"DifferentServedSchemas": {
+			reason:   "Served versions with different schemas and no webhook conversion should be warned about.",
+			versions: []extv1.CustomResourceDefinitionVersion{version("v1alpha1", true, schema("a")), version("v1", true, schema("b"))},
+			want:     1,
+		},
+		"SameServedSchemas": {
+			reason:   "Served versions with the same schema should not be warned about.",
+			versions: []extv1.CustomResourceDefinitionVersion{version("v1alpha1", true, schema("a")), version("v1", true, schema("a"))},
+		},
+		"UnservedVersion": {
+			reason:   "Versions that are not served should not be compared.",
+			versions: []extv1.CustomResourceDefinitionVersion{version("v1alpha1", false, schema("a")), version("v1", true, schema("b"))},
+		},
+		"WebhookConversion": {
+			reason:     "Versions converted by a webhook may have different schemas.",
+			versions:   []extv1.CustomResourceDefinitionVersion{version("v1alpha1", true, schema("a")), version("v1", true, schema("b"))},
+			conversion: &extv1.CustomResourceConversion{Strategy: extv1.WebhookConverter},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := &extv1.CustomResourceDefinition{Spec: extv1.CustomResourceDefinitionSpec{Versions: tc.versions, Conversion: tc.conversion}}
+			if got := versionWarnings(crd); len(got) != tc.want {
+				t.Errorf("\n%s\nversionWarnings(...): got %d warnings %q, want %d", tc.reason, len(got), got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidatePrinterColumns(t *testing.T) {
+	valid := extv1.CustomResourceColumnDefinition{Name: "SIZE", Type: "string", JSONPath: ".spec.size"}
+
+	cases := map[string]struct {
+		reason  string
+		column  extv1.CustomResourceColumnDefinition
+		wantErr string
+	}{
+		"Valid": {
+			reason: "Columns with a name, type and JSON path should be accepted.",
+			column: valid,
+		},
+		"NoName": {
+			reason:  "A column without a name should be reported by index.",
+			column:  extv1.CustomResourceColumnDefinition{Type: "string", JSONPath: ".spec.size"},
+			wantErr: "version v1 printer column 1 has no name",
+		},
+		"NoType": {
+			reason:  "A column without a type should be reported by index.",
+			column:  extv1.CustomResourceColumnDefinition{Name: "SIZE", JSONPath: ".spec.size"},
+			wantErr: "version v1 printer column 1 has no type",
+		},
+		"NoJSONPath": {
+			reason:  "A column without a JSON path should be reported by index.",
+			column:  extv1.CustomResourceColumnDefinition{Name: "SIZE", Type: "string"},
+			wantErr: "version v1 printer column 1 has no jsonPath",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			crd := &extv1.CustomResourceDefinition{Spec: extv1.CustomResourceDefinitionSpec{
+				Versions: []extv1.CustomResourceDefinitionVersion{{
+					Name:                     "v1",
+					AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{valid, tc.column},
+				}},
+			}}
+			err := validatePrinterColumns(crd)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.wantErr {
+				t.Errorf("\n%s\nvalidatePrinterColumns(...): got error %q, want %q", tc.reason, got, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckDangerousTypes(t *testing.T) {
+	rootPreserve := strings.Replace(baseXRD, "        type: object\n", "        type: object\n        x-kubernetes-preserve-unknown-fields: true\n", 1)
+	additional := baseXRD + `              labels:
+                type: object
+                additionalProperties: true
+`
+
+	cases := map[string]struct {
+		reason  string
+		xrd     string
+		allow   bool
+		wantErr string
+	}{
+		"Safe": {
+			reason: "A schema without risky constructs should be accepted.",
+			xrd:    baseXRD,
+		},
+		"RootPreserveUnknownFields": {
+			reason:  "Preserving unknown fields at the root should be rejected, naming the path.",
+			xrd:     rootPreserve,
+			wantErr: "uses x-kubernetes-preserve-unknown-fields at v1:openAPIV3Schema",
+		},
+		"RootPreserveUnknownFieldsAllowed": {
+			reason: "Preserving unknown fields at the root should be accepted with --allow-dangerous-types.",
+			xrd:    rootPreserve,
+			allow:  true,
+		},
+		"AdditionalProperties": {
+			reason:  "Allowing arbitrary additional properties should be rejected, naming the path.",
+			xrd:     additional,
+			wantErr: "uses additionalProperties: true at v1:spec.labels",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xrd, err := decodeXrd([]byte(tc.xrd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := testConfig()
+			cfg.allowDangerous = tc.allow
+			_, _, err = renderCrd(context.Background(), "xrd.yaml", xrd, cfg, compositeGenerator(), &fileReport{})
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("\n%s\nrenderCrd(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("\n%s\nrenderCrd(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// watchDebounce is how long to wait after a change to a definition before
+// regenerating CRDs. Editors often write a file more than once when saving.
+const watchDebounce = 200 * time.Millisecond
+
+const (
+	errWatch       = "cannot watch for changes"
+	errFmtWatchDir = "cannot watch %q"
+)
+
+// watchDefinitions regenerates CRDs whenever a definition beneath the input
+// directory is created or modified, until interrupted. Only the CRDs of
+// changed definitions are rewritten, as the rest are up to date.
+func watchDefinitions(ctx context.Context, cfg *config) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, errWatch)
+	}
+	defer w.Close()
+
+	if err := watchDirs(w, cfg.input, cfg.output); err != nil {
+		return err
+	}
+	fmt.Fprintf(messages, "Watching %s for changes to definitions\n", cfg.input)
+
+	// The timer only fires after a change, once changes stop for a while.
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	var changed string
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-w.Errors:
+			return errors.Wrap(err, errWatch)
+		case e := <-w.Events:
+			if e.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+				continue
+			}
+			if fi, err := os.Stat(e.Name); err == nil && fi.IsDir() {
+				if err := watchDirs(w, e.Name, cfg.output); err != nil {
+					return err
+				}
+				continue
+			}
+			if !matchesAny(cfg.patterns, filepath.Base(e.Name)) {
+				continue
+			}
+			changed = e.Name
+			timer.Reset(watchDebounce)
+		case <-timer.C:
+			fmt.Fprintf(messages, "Regenerating CRDs after a change to %s\n", changed)
+			if err := generateCrdsForPatterns(ctx, cfg.patterns, cfg.input, cfg); err != nil {
+				cfg.log.Errorf("Error regenerating CRDs %s", err)
+			}
+		}
+	}
+}
+
+// watchDirs adds root and every directory beneath it to the supplied watcher,
+// except hidden directories and the output directory, whose changes are
+// caused by the watcher itself.
+func watchDirs(w *fsnotify.Watcher, root, output string) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path == filepath.Clean(output) || (path != root && strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		return errors.Wrapf(w.Add(path), errFmtWatchDir, path)
+	})
+	return errors.Wrap(err, errWatch)
+}
+
+// matchesAny returns true if the supplied file name matches any of the
+// supplied patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
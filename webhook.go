@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	admv1 "k8s.io/api/admissionregistration/v1"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	errFmtInvalidWebhookService = "invalid webhook service %q: must be of the form namespace/name"
+	errFmtWriteWebhook          = "cannot write validating webhook configuration for %q"
+)
+
+// webhookService identifies the service that serves a validating webhook.
+type webhookService struct {
+	Namespace string
+	Name      string
+	Path      string
+}
+
+// parseWebhookService parses a service reference of the form namespace/name.
+func parseWebhookService(s, path string) (*webhookService, error) {
+	ns, name, ok := strings.Cut(s, "/")
+	if !ok || ns == "" || name == "" {
+		return nil, errors.Errorf(errFmtInvalidWebhookService, s)
+	}
+	return &webhookService{Namespace: ns, Name: name, Path: path}, nil
+}
+
+// ValidatingWebhookFor returns a ValidatingWebhookConfiguration stub that sends
+// creates and updates of the resources defined by the supplied CRD to the
+// supplied service. The CA bundle is left for the caller to fill in.
+func ValidatingWebhookFor(crd *extv1.CustomResourceDefinition, svc *webhookService) *admv1.ValidatingWebhookConfiguration {
+	versions := make([]string, 0, len(crd.Spec.Versions))
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			versions = append(versions, v.Name)
+		}
+	}
+	path := svc.Path
+	fail := admv1.Fail
+	none := admv1.SideEffectClassNone
+	scope := admv1.ClusterScope
+	if crd.Spec.Scope == extv1.NamespaceScoped {
+		scope = admv1.NamespacedScope
+	}
+
+	return &admv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admv1.SchemeGroupVersion.String(),
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: crd.GetName()},
+		Webhooks: []admv1.ValidatingWebhook{{
+			Name: fmt.Sprintf("validate.%s", crd.GetName()),
+			ClientConfig: admv1.WebhookClientConfig{
+				Service: &admv1.ServiceReference{
+					Namespace: svc.Namespace,
+					Name:      svc.Name,
+					Path:      &path,
+				},
+			},
+			Rules: []admv1.RuleWithOperations{{
+				Operations: []admv1.OperationType{admv1.Create, admv1.Update},
+				Rule: admv1.Rule{
+					APIGroups:   []string{crd.Spec.Group},
+					APIVersions: versions,
+					Resources:   []string{crd.Spec.Names.Plural},
+					Scope:       &scope,
+				},
+			}},
+			FailurePolicy:           &fail,
+			SideEffects:             &none,
+			AdmissionReviewVersions: []string{"v1"},
+		}},
+	}
+}
+
+// writeWebhook writes a ValidatingWebhookConfiguration stub for the supplied
+// CRD to the webhooks directory of the output folder, unless an identical stub
+// is already there.
+func writeWebhook(crd *extv1.CustomResourceDefinition, outputFolder string, svc *webhookService) error {
+	dir := filepath.Join(outputFolder, "webhooks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, errFmtWriteWebhook, crd.GetName())
+	}
+	y, err := yaml.Marshal(ValidatingWebhookFor(crd, svc))
+	if err != nil {
+		return errors.Wrapf(err, errFmtWriteWebhook, crd.GetName())
+	}
+	output := filepath.Join(dir, fmt.Sprintf("%s_%s.yaml", crd.Spec.Group, crd.Spec.Names.Plural))
+	if unchanged(output, y) {
+		return nil
+	}
+	return errors.Wrapf(ioutil.WriteFile(output, y, 0644), errFmtWriteWebhook, crd.GetName())
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admv1 "k8s.io/api/admissionregistration/v1"
+)
+
+func TestParseWebhookService(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		s       string
+		want    *webhookService
+		wantErr bool
+	}{
+		"Valid": {
+			reason: "A namespace and name should be parsed.",
+			s:      "system/validator",
+			want:   &webhookService{Namespace: "system", Name: "validator", Path: "/validate"},
+		},
+		"NoNamespace": {
+			reason:  "A bare name should be rejected.",
+			s:       "validator",
+			wantErr: true,
+		},
+		"EmptyName": {
+			reason:  "An empty name should be rejected.",
+			s:       "system/",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseWebhookService(tc.s, "/validate")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\nparseWebhookService(%q): got error %v, want error %t", tc.reason, tc.s, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nparseWebhookService(%q): -want, +got:\n%s", tc.reason, tc.s, diff)
+			}
+		})
+	}
+}
+
+func TestValidatingWebhookFor(t *testing.T) {
+	cluster, namespaced := admv1.ClusterScope, admv1.NamespacedScope
+	want := map[string]admv1.Rule{
+		"xthings.example.org": {APIGroups: []string{"example.org"}, APIVersions: []string{"v1"}, Resources: []string{"xthings"}, Scope: &cluster},
+		"things.example.org":  {APIGroups: []string{"example.org"}, APIVersions: []string{"v1"}, Resources: []string{"things"}, Scope: &namespaced},
+	}
+
+	svc := &webhookService{Namespace: "system", Name: "validator", Path: "/validate"}
+	for _, crd := range deriveCRDs(t, claimXRD, testConfig()) {
+		wh := ValidatingWebhookFor(crd, svc)
+		if wh.GetName() != crd.GetName() {
+			t.Errorf("\nThe webhook configuration should be named after its CRD.\nValidatingWebhookFor(%s): got name %q", crd.GetName(), wh.GetName())
+		}
+		if diff := cmp.Diff(want[crd.GetName()], wh.Webhooks[0].Rules[0].Rule); diff != "" {
+			t.Errorf("\nThe webhook should match the group, served versions and resource of its CRD.\nValidatingWebhookFor(%s): -want, +got rule:\n%s", crd.GetName(), diff)
+		}
+		ref := wh.Webhooks[0].ClientConfig.Service
+		if ref.Namespace != svc.Namespace || ref.Name != svc.Name || *ref.Path != svc.Path {
+			t.Errorf("\nThe webhook should call the configured service.\nValidatingWebhookFor(%s): got service %s/%s%s", crd.GetName(), ref.Namespace, ref.Name, *ref.Path)
+		}
+	}
+}
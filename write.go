@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	errFmtWriteCRD = "cannot write CRD %q"
+)
+
+// A renderFunc derives a CRD from a definition using the supplied generator and
+// returns it along with its encoding. It returns a nil CRD if the generator
+// skipped the definition.
+type renderFunc func(xrd *definition, generator generatorFunc) (*extv1.CustomResourceDefinition, []byte, error)
+
+// A sinkFunc writes a rendered CRD.
+type sinkFunc func(crd *extv1.CustomResourceDefinition, y []byte) error
+
+// WriteCRDs writes the CRD of the composite resource defined by the supplied
+// XRD, followed by the CRD of its claim if it offers one, to w as a stream of
+// YAML documents.
+func WriteCRDs(xrd *v1.CompositeResourceDefinition, w io.Writer, opts ...Option) error {
+	_, err := emitCRDs(&definition{CompositeResourceDefinition: xrd}, generatorsFor(opts, opts, nil), encodeIn(FormatYAML), streamTo(w, FormatYAML))
+	return err
+}
+
+// generatorsFor returns the generators of the composite resource CRD and the
+// claim CRD of a definition, which derive them using the supplied options. The
+// claim generator skips definitions that offer no claim, and says so to log
+// unless it is nil.
+func generatorsFor(compositeOpts, claimOpts []Option, log Logger) []generatorFunc {
+	return []generatorFunc{
+		func(xrd *definition) (*extv1.CustomResourceDefinition, error) {
+			return ForCompositeResource(xrd.CompositeResourceDefinition, xrd.compositeOptions(compositeOpts)...)
+		},
+		func(xrd *definition) (*extv1.CustomResourceDefinition, error) {
+			if xrd.Spec.ClaimNames == nil && newOptions(claimOpts).claimNamesConvention == "" {
+				if log != nil {
+					log.Infof("%s offers no claim, skipping its claim CRD", xrd.GetName())
+				}
+				return nil, nil
+			}
+			crd, err := ForCompositeResourceClaim(xrd.CompositeResourceDefinition, claimOpts...)
+			if err != nil {
+				return nil, err
+			}
+			// The composite resource CRD may have short names the definition
+			// doesn't, which its claim CRD must not share.
+			composite := shortNamesFor(xrd.Spec.Names.ShortNames, newOptions(compositeOpts))
+			if err := validateClaimShortNames(composite, crd.Spec.Names.ShortNames); err != nil {
+				return nil, errors.Wrap(err, errInvalidClaimNames)
+			}
+			return crd, nil
+		},
+	}
+}
+
+// emitCRDs renders the CRD each of the supplied generators derives from the
+// supplied definition and passes it to sink. It returns the CRDs it emitted,
+// which exclude those of generators that skipped the definition.
+func emitCRDs(xrd *definition, generators []generatorFunc, render renderFunc, sink sinkFunc) ([]*extv1.CustomResourceDefinition, error) {
+	var crds []*extv1.CustomResourceDefinition
+	for _, generator := range generators {
+		crd, y, err := render(xrd, generator)
+		if err != nil {
+			return crds, err
+		}
+		if crd == nil {
+			continue
+		}
+		if err := sink(crd, y); err != nil {
+			return crds, err
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
+
+// encodeIn returns a renderFunc that encodes CRDs in the supplied format
+// without checking them.
+func encodeIn(format string) renderFunc {
+	return func(xrd *definition, generator generatorFunc) (*extv1.CustomResourceDefinition, []byte, error) {
+		crd, err := generator(xrd)
+		if err != nil || crd == nil {
+			return nil, nil, err
+		}
+		crd.Kind = "CustomResourceDefinition"
+		crd.APIVersion = "apiextensions.k8s.io/v1"
+		y, err := encodeCRD(crd, format)
+		return crd, y, err
+	}
+}
+
+// streamTo returns a sinkFunc that writes CRDs to w as documents of a stream
+// in the supplied format.
+func streamTo(w io.Writer, format string) sinkFunc {
+	return func(crd *extv1.CustomResourceDefinition, y []byte) error {
+		return errors.Wrapf(writeDocumentIn(w, y, format), errFmtWriteCRD, crd.GetName())
+	}
+}
+
+// convertStream reads the definitions from r and writes the CRDs derived from
+// them to w, without touching the filesystem. Each CRD is rendered and checked
+// as it would be when converting files.
+func convertStream(ctx context.Context, r io.Reader, w io.Writer, cfg *config) error {
+	xrds, err := readXrds(r)
+	if err != nil {
+		return err
+	}
+	cfg.generated = map[string]string{}
+	generators := generatorsFor(cfg.compositeOptions(), cfg.claimOptions(), cfg.log)
+	for _, xrd := range xrds {
+		xrd.path = "-"
+		render := func(xrd *definition, generator generatorFunc) (*extv1.CustomResourceDefinition, []byte, error) {
+			return renderCrd(ctx, "-", xrd, cfg, generator, &fileReport{Input: "-", Output: "-"})
+		}
+		if _, err := emitCRDs(xrd, generators, render, streamTo(w, cfg.format)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCRD writes the supplied CRD to w as a single YAML document.
+func writeCRD(w io.Writer, crd *extv1.CustomResourceDefinition) error {
+	y, err := yaml.Marshal(crd)
+	if err != nil {
+		return errors.Wrapf(err, errFmtWriteCRD, crd.GetName())
+	}
+	_, err = w.Write(y)
+	return errors.Wrapf(err, errFmtWriteCRD, crd.GetName())
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const claimXRD = baseXRD + `  claimNames:
+    kind: Thing
+    plural: things
+`
+
+func TestWriteCRDs(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		xrd    string
+		opts   []Option
+		want   []string
+	}{
+		"NoClaim": {
+			reason: "A definition that offers no claim should be written as its composite resource CRD only.",
+			xrd:    baseXRD,
+			want:   []string{"xthings.example.org"},
+		},
+		"Claim": {
+			reason: "A definition that offers a claim should be written as its composite resource CRD followed by its claim CRD.",
+			xrd:    claimXRD,
+			want:   []string{"xthings.example.org", "things.example.org"},
+		},
+		"ClaimNamesConvention": {
+			reason: "A definition given claim names by convention should be written with its claim CRD.",
+			xrd:    baseXRD,
+			opts:   []Option{WithClaimNamesConvention(ClaimNamesStripX)},
+			want:   []string{"xthings.example.org", "things.example.org"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xrd, err := decodeXrd([]byte(tc.xrd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := &bytes.Buffer{}
+			if err := WriteCRDs(xrd.CompositeResourceDefinition, buf, tc.opts...); err != nil {
+				t.Fatalf("\n%s\nWriteCRDs(...): %v", tc.reason, err)
+			}
+
+			docs := strings.Split(buf.String(), "---\n")[1:]
+			if len(docs) != len(tc.want) {
+				t.Fatalf("\n%s\nWriteCRDs(...): wrote %d documents, want %d:\n%s", tc.reason, len(docs), len(tc.want), buf.String())
+			}
+			for i, doc := range docs {
+				var got struct {
+					Kind     string `json:"kind"`
+					Metadata struct {
+						Name string `json:"name"`
+					} `json:"metadata"`
+				}
+				if err := yaml.Unmarshal([]byte(doc), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Kind != "CustomResourceDefinition" || got.Metadata.Name != tc.want[i] {
+					t.Errorf("\n%s\nWriteCRDs(...): document %d is %s %q, want CustomResourceDefinition %q", tc.reason, i+1, got.Kind, got.Metadata.Name, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConvertStream(t *testing.T) {
+	dangerous := strings.Replace(claimXRD, "            properties:\n              base:", "            x-kubernetes-preserve-unknown-fields: true\n            properties:\n              base:", 1)
+
+	cases := map[string]struct {
+		reason  string
+		in      string
+		cfg     func(cfg *config)
+		want    string
+		wantErr string
+	}{
+		"Checked": {
+			reason:  "CRDs read from a stream should be checked like those read from files.",
+			in:      dangerous,
+			wantErr: "pass --allow-dangerous-types",
+		},
+		"Format": {
+			reason: "CRDs read from a stream should be written in the requested format.",
+			in:     claimXRD,
+			cfg:    func(cfg *config) { cfg.format = FormatJSON },
+			want:   `"name": "things.example.org"`,
+		},
+		"Options": {
+			reason: "CRDs read from a stream should be derived using the configured options.",
+			in:     dangerous,
+			cfg: func(cfg *config) {
+				cfg.allowDangerous = true
+				cfg.compositeShortNames = stringsFlag{"xth"}
+			},
+			want: "- xth\n",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.outputMode = OutputStdout
+			if tc.cfg != nil {
+				tc.cfg(cfg)
+			}
+			buf := &bytes.Buffer{}
+			err := convertStream(context.Background(), strings.NewReader(tc.in), buf, cfg)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("\n%s\nconvertStream(...): %v", tc.reason, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("\n%s\nconvertStream(...): got error %v, want one containing %q", tc.reason, err, tc.wantErr)
+			}
+			if !strings.Contains(buf.String(), tc.want) {
+				t.Errorf("\n%s\nconvertStream(...): output lacks %q:\n%s", tc.reason, tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteCRDsDeterministic(t *testing.T) {
+	shortNames := strings.Replace(claimXRD, "    plural: xthings\n", "    plural: xthings\n    shortNames: [xt, xth]\n", 1) + "    shortNames: [th, thg]\n"
+	xrd := strings.Replace(shortNames, `            properties:
+              base:
+                type: string
+`, `            required: [zeta, alpha, mid]
+            properties:
+              base:
+                type: string
+              zeta:
+                type: string
+              alpha:
+                type: string
+              mid:
+                type: object
+                required: [b, a]
+                properties:
+                  a:
+                    type: string
+                  b:
+                    type: string
+          status:
+            type: object
+            required: [ready, phase]
+            properties:
+              ready:
+                type: boolean
+              phase:
+                type: string
+`, 1)
+
+	d, err := decodeXrd([]byte(xrd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	convert := func() string {
+		buf := &bytes.Buffer{}
+		if err := WriteCRDs(d.CompositeResourceDefinition, buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	want := convert()
+	// Map iteration order varies between iterations, so a few conversions
+	// are compared to catch output that depends on it.
+	for i := 0; i < 10; i++ {
+		if got := convert(); got != want {
+			t.Fatalf("\nConverting a definition twice should write identical bytes.\nWriteCRDs(...): -want, +got:\n%s", cmp.Diff(want, got))
+		}
+	}
+	var unsorted func(field string, s extv1.JSONSchemaProps) []string
+	unsorted = func(field string, s extv1.JSONSchemaProps) []string {
+		var fields []string
+		if !sort.StringsAreSorted(s.Required) {
+			fields = append(fields, field)
+		}
+		for _, k := range sortedKeys(s.Properties) {
+			fields = append(fields, unsorted(field+"."+k, s.Properties[k])...)
+		}
+		return fields
+	}
+	for _, doc := range strings.Split(want, "---\n")[1:] {
+		crd := &extv1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal([]byte(doc), crd); err != nil {
+			t.Fatal(err)
+		}
+		if fields := unsorted(crd.GetName(), *crd.Spec.Versions[0].Schema.OpenAPIV3Schema); len(fields) > 0 {
+			t.Errorf("\nRequired fields should be sorted.\nWriteCRDs(...): unsorted required fields of %v", fields)
+		}
+	}
+}